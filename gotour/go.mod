@@ -1,5 +1,5 @@
 module gotour
 
-go 1.22.5
+go 1.23
 
 require golang.org/x/tour v0.1.0
@@ -0,0 +1,235 @@
+package idiomaticgo
+
+/*
+Alerting
+========
+
+The StateMonitor is the only goroutine that sees every State, so it is also
+the natural place to notice when a URL goes down or comes back up. For each
+URL it remembers whether the URL was up at the last poll and when it last
+changed. Each State received on updates is passed to an alerter, which
+decides whether the change is worth telling anyone about:
+
+    down      the URL was up and a poll failed or got a server error (5xx)
+    up        the URL was down and a poll got any other response
+    flapping  the URL changed state flapChanges times within flapWindow
+
+Two rules keep the noise down. An alert is only sent when it differs from
+the last alert sent for the URL (deduplication), and no alert is sent for a
+URL within quietPeriod of the previous one. A change that happens during the
+quiet period is not lost: it is reported on the first poll after the quiet
+period ends, if the URL is still in that state.
+
+Notifiers
+=========
+
+Alerts are delivered by Notifiers. A Notifier is an interface with a single
+Notify method, so anything can receive alerts; this file provides a webhook,
+an email and a command notifier, and the NotifierFunc adapter turns an
+ordinary function into a Notifier.
+
+Notifying can be slow (it talks to other machines), so the monitor goroutine
+does not call Notify itself. It sends alerts on a buffered channel to a
+dispatcher goroutine, which calls every Notifier in turn.
+*/
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/smtp"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+const (
+	quietPeriod = 15 * time.Minute // minimum time between alerts for a URL
+	flapWindow  = 30 * time.Minute // window in which state changes are counted
+	flapChanges = 4                // state changes within flapWindow that mean flapping
+	alertQueue  = 16               // alerts buffered for the dispatcher
+)
+
+// A Transition is a change in the state of a URL worth alerting about.
+type Transition int
+
+const (
+	TransitionUp Transition = iota
+	TransitionDown
+	TransitionFlapping
+)
+
+func (t Transition) String() string {
+	switch t {
+	case TransitionUp:
+		return "up"
+	case TransitionDown:
+		return "down"
+	case TransitionFlapping:
+		return "flapping"
+	}
+	return "unknown"
+}
+
+func (t Transition) MarshalText() ([]byte, error) {
+	return []byte(t.String()), nil
+}
+
+// An Alert reports a Transition of a URL.
+type Alert struct {
+	URL        string     `json:"url"`
+	Transition Transition `json:"transition"`
+	Status     string     `json:"status"`
+	Time       time.Time  `json:"time"`
+}
+
+func (a Alert) String() string {
+	return fmt.Sprintf("%s is %s (%s)", a.URL, a.Transition, a.Status)
+}
+
+// A Notifier delivers alerts.
+type Notifier interface {
+	Notify(a Alert) error
+}
+
+// The NotifierFunc type is an adapter to allow the use of ordinary
+// functions as Notifiers.
+type NotifierFunc func(a Alert) error
+
+// Notify calls f(a).
+func (f NotifierFunc) Notify(a Alert) error {
+	return f(a)
+}
+
+// WebhookNotifier POSTs each alert as JSON to URL.
+type WebhookNotifier struct {
+	URL    string
+	Client *http.Client // http.DefaultClient if nil
+}
+
+func (n WebhookNotifier) Notify(a Alert) error {
+	body, err := json.Marshal(a)
+	if err != nil {
+		return err
+	}
+	client := n.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Post(n.URL, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("webhook %s: %s", n.URL, resp.Status)
+	}
+	return nil
+}
+
+// EmailNotifier mails each alert through the SMTP server at Addr.
+type EmailNotifier struct {
+	Addr string // host:port of the SMTP server
+	Auth smtp.Auth
+	From string
+	To   []string
+}
+
+func (n EmailNotifier) Notify(a Alert) error {
+	var msg bytes.Buffer
+	fmt.Fprintf(&msg, "From: %s\r\n", n.From)
+	fmt.Fprintf(&msg, "To: %s\r\n", strings.Join(n.To, ", "))
+	fmt.Fprintf(&msg, "Subject: %s is %s\r\n", a.URL, a.Transition)
+	fmt.Fprintf(&msg, "\r\n%s at %s\r\n", a, a.Time.Format(time.RFC1123))
+	return smtp.SendMail(n.Addr, n.Auth, n.From, n.To, msg.Bytes())
+}
+
+// CommandNotifier runs a local command for each alert. The alert is passed
+// to the command in the ALERT_URL, ALERT_TRANSITION, ALERT_STATUS and
+// ALERT_TIME environment variables.
+type CommandNotifier struct {
+	Name string
+	Args []string
+}
+
+func (n CommandNotifier) Notify(a Alert) error {
+	cmd := exec.Command(n.Name, n.Args...)
+	cmd.Env = append(os.Environ(),
+		"ALERT_URL="+a.URL,
+		"ALERT_TRANSITION="+a.Transition.String(),
+		"ALERT_STATUS="+a.Status,
+		"ALERT_TIME="+a.Time.Format(time.RFC3339),
+	)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("%s: %v: %s", n.Name, err, out)
+	}
+	return nil
+}
+
+// dispatch calls every notifier for each alert received on alerts.
+func dispatch(alerts <-chan Alert, notifiers []Notifier) {
+	for a := range alerts {
+		for _, n := range notifiers {
+			if err := n.Notify(a); err != nil {
+				log.Println("Error notifying", a, err)
+			}
+		}
+	}
+}
+
+// urlHistory is what the alerter remembers about a URL.
+type urlHistory struct {
+	up         bool
+	changes    []time.Time // times of the state changes within flapWindow
+	notified   Transition  // last transition alerted
+	notifiedAt time.Time
+}
+
+// An alerter detects transitions in the States seen by the StateMonitor.
+// Like urlStatus, it is owned by the monitor goroutine.
+type alerter struct {
+	urls map[string]*urlHistory
+}
+
+func newAlerter() *alerter {
+	return &alerter{urls: make(map[string]*urlHistory)}
+}
+
+// observe records s and reports whether it should raise an alert.
+// URLs are presumed up until their first poll.
+func (a *alerter) observe(s State) (Alert, bool) {
+	h, ok := a.urls[s.url]
+	if !ok {
+		h = &urlHistory{up: true, notified: TransitionUp}
+		a.urls[s.url] = h
+	}
+	now := s.checked
+	if up := s.up(); up != h.up {
+		h.up = up
+		h.changes = append(h.changes, now)
+	}
+	for len(h.changes) > 0 && now.Sub(h.changes[0]) > flapWindow {
+		h.changes = h.changes[1:]
+	}
+
+	var t Transition
+	switch {
+	case len(h.changes) >= flapChanges:
+		t = TransitionFlapping
+	case h.up:
+		t = TransitionUp
+	default:
+		t = TransitionDown
+	}
+	if t == h.notified {
+		return Alert{}, false
+	}
+	if !h.notifiedAt.IsZero() && now.Sub(h.notifiedAt) < quietPeriod {
+		return Alert{}, false
+	}
+	h.notified, h.notifiedAt = t, now
+	return Alert{URL: s.url, Transition: t, Status: s.status, Time: now}, true
+}
@@ -0,0 +1,229 @@
+package idiomaticgo
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testURL = "http://example.com/"
+
+var epoch = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// upState and downState return the State of a successful and a failed poll
+// of testURL made at epoch+at.
+func upState(at time.Duration) State {
+	return State{url: testURL, status: "200 OK", checked: epoch.Add(at), code: 200}
+}
+
+func downState(at time.Duration) State {
+	return State{url: testURL, status: "connection refused", checked: epoch.Add(at), errClass: "connection", errCount: 1}
+}
+
+func TestAlerterObserve(t *testing.T) {
+	type want struct {
+		alert bool
+		t     Transition
+	}
+	none := want{}
+	tests := []struct {
+		name   string
+		states []State
+		want   []want
+	}{
+		{
+			name:   "stays up",
+			states: []State{upState(0), upState(time.Minute)},
+			want:   []want{none, none},
+		},
+		{
+			name:   "down and up",
+			states: []State{upState(0), downState(time.Minute), upState(20 * time.Minute)},
+			want:   []want{none, {true, TransitionDown}, {true, TransitionUp}},
+		},
+		{
+			name: "server error",
+			states: []State{
+				upState(0),
+				{url: testURL, status: "503 Service Unavailable", checked: epoch.Add(time.Minute), code: 503},
+				{url: testURL, status: "404 Not Found", checked: epoch.Add(20 * time.Minute), code: 404},
+			},
+			want: []want{none, {true, TransitionDown}, {true, TransitionUp}},
+		},
+		{
+			name:   "down once",
+			states: []State{downState(0), downState(time.Minute), downState(20 * time.Minute)},
+			want:   []want{{true, TransitionDown}, none, none},
+		},
+		{
+			name: "quiet period",
+			states: []State{
+				downState(0),
+				upState(time.Minute),      // within the quiet period
+				upState(10 * time.Minute), // still within it
+				upState(16 * time.Minute), // reported now
+			},
+			want: []want{{true, TransitionDown}, none, none, {true, TransitionUp}},
+		},
+		{
+			name: "change lost in quiet period",
+			states: []State{
+				downState(0),
+				upState(time.Minute),
+				downState(2 * time.Minute),
+				downState(16 * time.Minute), // down again: nothing new to say
+			},
+			want: []want{{true, TransitionDown}, none, none, none},
+		},
+		{
+			name: "flapping",
+			states: []State{
+				downState(0),
+				upState(5 * time.Minute),
+				downState(10 * time.Minute),
+				upState(16 * time.Minute), // fourth change within flapWindow
+				upState(20 * time.Minute),
+			},
+			want: []want{{true, TransitionDown}, none, none, {true, TransitionFlapping}, none},
+		},
+		{
+			name: "flapping ends",
+			states: []State{
+				downState(0),
+				upState(5 * time.Minute),
+				downState(10 * time.Minute),
+				upState(16 * time.Minute),
+				upState(50 * time.Minute), // the changes are older than flapWindow
+			},
+			want: []want{{true, TransitionDown}, none, none, {true, TransitionFlapping}, {true, TransitionUp}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := newAlerter()
+			for i, s := range tt.states {
+				alert, ok := a.observe(s)
+				if ok != tt.want[i].alert || ok && alert.Transition != tt.want[i].t {
+					t.Fatalf("state %d: got %v, %v; want %v", i, alert, ok, tt.want[i])
+				}
+				if ok && (alert.URL != s.url || !alert.Time.Equal(s.checked) || alert.Status != s.status) {
+					t.Errorf("state %d: alert %+v does not describe %+v", i, alert, s)
+				}
+			}
+		})
+	}
+}
+
+func TestWebhookNotifier(t *testing.T) {
+	received := make(chan map[string]any, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if ct := r.Header.Get("Content-Type"); r.Method != http.MethodPost || ct != "application/json" {
+			t.Errorf("got %s with Content-Type %q; want a JSON POST", r.Method, ct)
+		}
+		var body map[string]any
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Error(err)
+		}
+		received <- body
+	}))
+	defer srv.Close()
+
+	a := Alert{URL: testURL, Transition: TransitionDown, Status: "503 Service Unavailable", Time: epoch}
+	if err := (WebhookNotifier{URL: srv.URL, Client: srv.Client()}).Notify(a); err != nil {
+		t.Fatal(err)
+	}
+	body := <-received
+	if body["url"] != testURL || body["transition"] != "down" || body["status"] != a.Status {
+		t.Errorf("webhook received %v", body)
+	}
+
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "no", http.StatusInternalServerError)
+	}))
+	defer failing.Close()
+	if err := (WebhookNotifier{URL: failing.URL}).Notify(a); err == nil {
+		t.Error("Notify succeeded on a 500 response")
+	}
+}
+
+// smtpStub accepts one SMTP session on l and sends the message it receives
+// on messages.
+func smtpStub(t *testing.T, l net.Listener, messages chan<- string) {
+	conn, err := l.Accept()
+	if err != nil {
+		t.Error(err)
+		close(messages)
+		return
+	}
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	reply := func(s string) { io.WriteString(conn, s+"\r\n") }
+	reply("220 localhost stub")
+	var data strings.Builder
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			close(messages)
+			return
+		}
+		cmd := strings.ToUpper(strings.TrimSpace(line))
+		switch {
+		case strings.HasPrefix(cmd, "EHLO"), strings.HasPrefix(cmd, "HELO"):
+			reply("250 localhost")
+		case strings.HasPrefix(cmd, "MAIL FROM:"), strings.HasPrefix(cmd, "RCPT TO:"):
+			data.WriteString(strings.TrimSpace(line) + "\n")
+			reply("250 OK")
+		case cmd == "DATA":
+			reply("354 go ahead")
+			for {
+				line, err := r.ReadString('\n')
+				if err != nil || line == ".\r\n" {
+					break
+				}
+				data.WriteString(line)
+			}
+			reply("250 OK")
+		case cmd == "QUIT":
+			reply("221 bye")
+			messages <- data.String()
+			return
+		default:
+			reply("502 not implemented")
+		}
+	}
+}
+
+func TestEmailNotifier(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	messages := make(chan string, 1)
+	go smtpStub(t, l, messages)
+
+	n := EmailNotifier{Addr: l.Addr().String(), From: "monitor@example.com", To: []string{"ops@example.com", "dev@example.com"}}
+	a := Alert{URL: testURL, Transition: TransitionUp, Status: "200 OK", Time: epoch}
+	if err := n.Notify(a); err != nil {
+		t.Fatal(err)
+	}
+	msg := <-messages
+	for _, want := range []string{
+		"MAIL FROM:<monitor@example.com>",
+		"RCPT TO:<ops@example.com>",
+		"RCPT TO:<dev@example.com>",
+		"To: ops@example.com, dev@example.com\r\n",
+		"Subject: " + testURL + " is up\r\n",
+		a.String(),
+	} {
+		if !strings.Contains(msg, want) {
+			t.Errorf("message does not contain %q:\n%s", want, msg)
+		}
+	}
+}
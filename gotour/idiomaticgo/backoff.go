@@ -0,0 +1,216 @@
+package idiomaticgo
+
+/*
+Backoff policies
+================
+
+After a failed poll a Resource waits longer before it is polled again. How much
+longer is decided by a Backoff policy: an interface with a single method that
+maps the number of consecutive errors (and the previous delay) to an extra
+delay.
+
+    ConstantBackoff     the same delay after every error
+    LinearBackoff       a delay proportional to the number of errors
+    ExponentialBackoff  a delay that doubles with every error, optionally
+                        randomized ("jittered") so that many failing
+                        Resources do not retry in lockstep
+
+Every policy can be capped with a maximum delay.
+
+Circuit breaking
+================
+
+A Breaker stops a Resource from being polled at all while its URL keeps
+failing. It starts closed; after Threshold consecutive failures it opens and
+polls are skipped for Cooldown. Then it becomes half-open and lets a single
+poll through: if that poll succeeds the circuit closes again, otherwise it
+reopens.
+
+A Breaker belongs to its Resource, so like the rest of the Resource it is
+only touched by the goroutine that currently owns the Resource. Its state is
+sent to the StateMonitor along with every State, which logs transitions.
+*/
+
+import (
+	"math"
+	"math/rand"
+	"time"
+)
+
+// A Backoff computes the extra delay before the next poll of a Resource
+// that has failed errCount times in a row. prev is the delay it returned
+// last time.
+type Backoff interface {
+	Delay(errCount int, prev time.Duration) time.Duration
+}
+
+// ConstantBackoff waits Interval after every error.
+type ConstantBackoff struct {
+	Interval time.Duration
+}
+
+func (b ConstantBackoff) Delay(errCount int, prev time.Duration) time.Duration {
+	if errCount == 0 {
+		return 0
+	}
+	return b.Interval
+}
+
+// LinearBackoff waits Step for every consecutive error, up to Max.
+// A zero Max means no cap.
+type LinearBackoff struct {
+	Step, Max time.Duration
+}
+
+func (b LinearBackoff) Delay(errCount int, prev time.Duration) time.Duration {
+	if errCount > 0 && b.Step > 0 && b.Max > 0 && time.Duration(errCount) >= b.Max/b.Step {
+		return b.Max
+	}
+	return b.Step * time.Duration(errCount)
+}
+
+// Jitter selects how ExponentialBackoff randomizes its delays.
+type Jitter int
+
+const (
+	NoJitter           Jitter = iota // Base * 2^(errCount-1)
+	FullJitter                       // uniform in [0, min(Base * 2^(errCount-1), Max)]
+	DecorrelatedJitter               // uniform in [Base, 3 * prev]
+)
+
+// ExponentialBackoff doubles its delay after every error, up to Max.
+// A zero Max means no cap. The jitter is drawn from Rand, or from the
+// global source of math/rand if Rand is nil; like the Resource, a Rand
+// must only be used by one goroutine at a time.
+type ExponentialBackoff struct {
+	Base, Max time.Duration
+	Jitter    Jitter
+	Rand      *rand.Rand
+}
+
+func (b ExponentialBackoff) Delay(errCount int, prev time.Duration) time.Duration {
+	if errCount == 0 {
+		return 0
+	}
+	var d time.Duration
+	switch b.Jitter {
+	case DecorrelatedJitter:
+		hi := max(3*prev, b.Base)
+		d = b.Base + randDuration(b.Rand, hi-b.Base)
+	case FullJitter:
+		d = randDuration(b.Rand, b.exp(errCount))
+	default:
+		d = b.exp(errCount)
+	}
+	if b.Max > 0 && d > b.Max {
+		d = b.Max
+	}
+	return d
+}
+
+// exp returns Base * 2^(errCount-1), capped at Max and stopping early on
+// overflow. Capping before the jitter keeps FullJitter uniform up to Max.
+func (b ExponentialBackoff) exp(errCount int) time.Duration {
+	d := b.Base
+	for i := 1; i < errCount; i++ {
+		if (b.Max > 0 && d >= b.Max) || d > math.MaxInt64/2 {
+			break
+		}
+		d *= 2
+	}
+	if b.Max > 0 && d > b.Max {
+		d = b.Max
+	}
+	return d
+}
+
+// randDuration returns a random duration in [0, d] drawn from r, or from
+// the global source if r is nil.
+func randDuration(r *rand.Rand, d time.Duration) time.Duration {
+	int63, int63n := rand.Int63, rand.Int63n
+	if r != nil {
+		int63, int63n = r.Int63, r.Int63n
+	}
+	if d <= 0 {
+		return 0
+	}
+	if d == math.MaxInt64 {
+		return time.Duration(int63())
+	}
+	return time.Duration(int63n(int64(d) + 1))
+}
+
+// BreakerState is the state of a circuit Breaker.
+type BreakerState int
+
+const (
+	BreakerClosed   BreakerState = iota // polls go through
+	BreakerOpen                         // polls are skipped
+	BreakerHalfOpen                     // a single trial poll goes through
+)
+
+func (s BreakerState) String() string {
+	switch s {
+	case BreakerClosed:
+		return "closed"
+	case BreakerOpen:
+		return "open"
+	case BreakerHalfOpen:
+		return "half-open"
+	}
+	return "unknown"
+}
+
+// A Breaker is a circuit breaker guarding a single Resource.
+// A nil *Breaker is always closed.
+type Breaker struct {
+	Threshold int           // consecutive failures that open the circuit
+	Cooldown  time.Duration // how long the circuit stays open
+
+	state    BreakerState
+	failures int
+	openedAt time.Time
+}
+
+// State returns the current state of the breaker.
+func (b *Breaker) State() BreakerState {
+	if b == nil {
+		return BreakerClosed
+	}
+	return b.state
+}
+
+// Allow reports whether a poll may be made at time now. An open breaker
+// whose cooldown has elapsed becomes half-open and allows one poll.
+func (b *Breaker) Allow(now time.Time) bool {
+	if b == nil {
+		return true
+	}
+	if b.state == BreakerOpen && now.Sub(b.openedAt) >= b.Cooldown {
+		b.state = BreakerHalfOpen
+	}
+	return b.state != BreakerOpen
+}
+
+// Success records a successful poll, closing the circuit.
+func (b *Breaker) Success() {
+	if b == nil {
+		return
+	}
+	b.state = BreakerClosed
+	b.failures = 0
+}
+
+// Failure records a failed poll at time now. The circuit opens when the
+// trial poll of a half-open breaker fails or when Threshold consecutive
+// polls have failed.
+func (b *Breaker) Failure(now time.Time) {
+	if b == nil {
+		return
+	}
+	b.failures++
+	if b.state == BreakerHalfOpen || b.failures >= b.Threshold {
+		b.state = BreakerOpen
+		b.openedAt = now
+	}
+}
@@ -0,0 +1,128 @@
+package idiomaticgo
+
+import (
+	"math/rand"
+	"testing"
+	"time"
+)
+
+func TestBackoffDelay(t *testing.T) {
+	for _, tt := range []struct {
+		name   string
+		policy Backoff
+		want   []time.Duration // delays after 0, 1, 2, ... consecutive errors
+	}{
+		{"constant", ConstantBackoff{Interval: time.Second},
+			[]time.Duration{0, time.Second, time.Second, time.Second}},
+		{"linear", LinearBackoff{Step: 2 * time.Second},
+			[]time.Duration{0, 2 * time.Second, 4 * time.Second, 6 * time.Second}},
+		{"linear capped", LinearBackoff{Step: time.Second, Max: 3 * time.Second},
+			[]time.Duration{0, time.Second, 2 * time.Second, 3 * time.Second, 3 * time.Second}},
+		{"exponential", ExponentialBackoff{Base: time.Second},
+			[]time.Duration{0, time.Second, 2 * time.Second, 4 * time.Second, 8 * time.Second}},
+		{"exponential capped", ExponentialBackoff{Base: time.Second, Max: 5 * time.Second},
+			[]time.Duration{0, time.Second, 2 * time.Second, 4 * time.Second, 5 * time.Second, 5 * time.Second}},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			var prev time.Duration
+			for n, want := range tt.want {
+				got := tt.policy.Delay(n, prev)
+				if got != want {
+					t.Errorf("Delay(%d, %v) = %v; want %v", n, prev, got, want)
+				}
+				prev = got
+			}
+		})
+	}
+}
+
+func TestBackoffJitter(t *testing.T) {
+	const base, maxDelay = time.Second, 20 * time.Second
+	for _, tt := range []struct {
+		name   string
+		jitter Jitter
+		bounds func(n int, prev time.Duration) (lo, hi time.Duration)
+	}{
+		{"full", FullJitter, func(n int, prev time.Duration) (time.Duration, time.Duration) {
+			return 0, min(base<<min(n-1, 10), maxDelay)
+		}},
+		{"decorrelated", DecorrelatedJitter, func(n int, prev time.Duration) (time.Duration, time.Duration) {
+			return base, min(max(3*prev, base), maxDelay)
+		}},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			delays := func(seed int64) []time.Duration {
+				b := ExponentialBackoff{Base: base, Max: maxDelay, Jitter: tt.jitter, Rand: rand.New(rand.NewSource(seed))}
+				var ds []time.Duration
+				var prev time.Duration
+				for n := 1; n <= 50; n++ {
+					d := b.Delay(n, prev)
+					if lo, hi := tt.bounds(n, prev); d < lo || d > hi {
+						t.Fatalf("Delay(%d, %v) = %v; want in [%v, %v]", n, prev, d, lo, hi)
+					}
+					ds = append(ds, d)
+					prev = d
+				}
+				return ds
+			}
+			first, again := delays(1), delays(1)
+			distinct := make(map[time.Duration]bool)
+			for i := range first {
+				if first[i] != again[i] {
+					t.Fatalf("delay %d differs with the same seed: %v and %v", i+1, first[i], again[i])
+				}
+				distinct[first[i]] = true
+			}
+			if len(distinct) < 10 {
+				t.Errorf("only %d distinct delays in %v", len(distinct), first)
+			}
+		})
+	}
+}
+
+func TestBreakerTransitions(t *testing.T) {
+	b := &Breaker{Threshold: 3, Cooldown: time.Minute}
+	now := epoch
+	for _, step := range []struct {
+		after   time.Duration // time since the previous step
+		op      string        // "allow", "success" or "failure"
+		allowed bool          // result of "allow"
+		want    BreakerState
+	}{
+		{0, "allow", true, BreakerClosed},
+		{0, "failure", false, BreakerClosed},
+		{0, "failure", false, BreakerClosed},
+		{0, "success", false, BreakerClosed}, // resets the count
+		{0, "failure", false, BreakerClosed},
+		{0, "failure", false, BreakerClosed},
+		{0, "failure", false, BreakerOpen},
+		{30 * time.Second, "allow", false, BreakerOpen},
+		{30 * time.Second, "allow", true, BreakerHalfOpen},
+		{0, "failure", false, BreakerOpen}, // the trial poll failed
+		{59 * time.Second, "allow", false, BreakerOpen},
+		{time.Second, "allow", true, BreakerHalfOpen},
+		{0, "success", false, BreakerClosed},
+		{0, "failure", false, BreakerClosed},
+	} {
+		now = now.Add(step.after)
+		switch step.op {
+		case "allow":
+			if got := b.Allow(now); got != step.allowed {
+				t.Errorf("at %v: Allow = %v; want %v", now.Sub(epoch), got, step.allowed)
+			}
+		case "success":
+			b.Success()
+		case "failure":
+			b.Failure(now)
+		}
+		if b.State() != step.want {
+			t.Fatalf("at %v after %s: state %s; want %s", now.Sub(epoch), step.op, b.State(), step.want)
+		}
+	}
+
+	var nilBreaker *Breaker
+	nilBreaker.Failure(now)
+	if !nilBreaker.Allow(now) || nilBreaker.State() != BreakerClosed {
+		t.Error("nil Breaker is not always closed")
+	}
+}
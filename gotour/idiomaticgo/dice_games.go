@@ -0,0 +1,323 @@
+package idiomaticgo
+
+/*
+Beyond Pig
+==========
+
+The score, action and strategy types above are made for Pig: two players, one
+die and 100 points to win. Many dice games share the same shape, however: on
+their turn players keep rolling to build up a turn total, which they either
+bank or lose. A ruleset captures what differs between them.
+
+The game state
+==============
+
+A gameState generalizes score to any number of players: the banked score of
+every player, whose turn it is and the points accumulated in this turn.
+
+Rulesets
+========
+
+A ruleset has a name, a target score, the most dice a player may roll at once,
+and two functions returning gameActions: roll, for rolling n dice, and stay.
+A gameAction is the generalization of action to gameState.
+
+    Pig           the original game, played by the roll and stay functions
+                  above: any number of players take turns at the 2-player
+                  score, with every other player seen as the opponent
+    Two-Dice Pig  two dice; a single 1 loses the turn total, two 1s lose the
+                  player's whole score
+    Big Pig       two dice; a single 1 loses the turn total, two 1s add 25 and
+                  any other double adds twice the sum of the dice
+    Hog           one roll per turn of as many dice as the player likes; the
+                  sum is banked unless any die shows a 1
+
+Strategies
+==========
+
+A gameStrategy chooses an action from the ruleset for a game state. holdAt is
+stayAtK for every ruleset, and hogDice is the natural family of strategies for
+Hog. Both take a parameter k of at least 1: a player who stayed on a turn
+total of 0 would never end their turn, and one rolling no dice would never
+score.
+*/
+
+import (
+	"fmt"
+	"math/rand"
+	"sort"
+	"strings"
+)
+
+// A gameState is the state of a dice game between any number of players.
+type gameState struct {
+	scores   []int // banked score of each player
+	current  int   // index of the player to move
+	thisTurn int   // points accumulated in this turn
+}
+
+// clone returns a copy of gs that shares no memory with it.
+func (gs gameState) clone() gameState {
+	gs.scores = append([]int(nil), gs.scores...)
+	return gs
+}
+
+// endTurn banks points for the current player and passes the turn on.
+func (gs *gameState) endTurn(points int) {
+	gs.scores[gs.current] += points
+	gs.current = (gs.current + 1) % len(gs.scores)
+	gs.thisTurn = 0
+}
+
+// score returns the 2-player view of gs: the current player against the
+// best of the others.
+func (gs gameState) score() score {
+	opponent := 0
+	for p, s := range gs.scores {
+		if p != gs.current {
+			opponent = max(opponent, s)
+		}
+	}
+	return score{gs.scores[gs.current], opponent, gs.thisTurn}
+}
+
+// A gameAction transitions stochastically to a resulting game state.
+type gameAction func(current gameState, rng *rand.Rand) (result gameState, turnIsOver bool)
+
+// A gameStrategy chooses an action of the rules for any game state.
+type gameStrategy func(r *ruleset, gs gameState) gameAction
+
+// A ruleset defines a dice game.
+type ruleset struct {
+	name    string
+	target  int // the winning score
+	maxDice int // the most dice that may be rolled at once
+	roll    func(n int) gameAction
+	stay    gameAction
+
+	// family returns the kth member of the usual strategies for the game,
+	// for k from 1 to maxK (unbounded if 0), and familyName describes it.
+	family     func(k int) gameStrategy
+	familyName string
+	maxK       int
+}
+
+// fromAction turns a Pig action into a gameAction. Because the players'
+// roles swap at the end of a turn, result.opponent is the mover's new
+// banked score.
+func fromAction(a action) gameAction {
+	return func(gs gameState, rng *rand.Rand) (gameState, bool) {
+		gs = gs.clone()
+		result, turnIsOver := a(gs.score(), rng)
+		if turnIsOver {
+			gs.scores[gs.current] = result.opponent
+			gs.endTurn(0)
+		} else {
+			gs.thisTurn = result.thisTurn
+		}
+		return gs, turnIsOver
+	}
+}
+
+// rollDice returns the values of n dice.
+func rollDice(rng *rand.Rand, n int) []int {
+	dice := make([]int, n)
+	for k := range dice {
+		dice[k] = rng.Intn(6) + 1
+	}
+	return dice
+}
+
+// stayAction banks the turn total. It is the stay of every ruleset.
+func stayAction(gs gameState, rng *rand.Rand) (gameState, bool) {
+	gs = gs.clone()
+	gs.endTurn(gs.thisTurn)
+	return gs, true
+}
+
+// twoDiceRoll returns the roll action of a two-dice game, where doubleOne
+// and double handle rolls of two 1s and of other doubles, and any other roll
+// containing a 1 loses the turn total.
+func twoDiceRoll(doubleOne, double func(gs *gameState, die int) bool) func(int) gameAction {
+	return func(int) gameAction {
+		return func(gs gameState, rng *rand.Rand) (gameState, bool) {
+			gs = gs.clone()
+			d := rollDice(rng, 2)
+			switch {
+			case d[0] == 1 && d[1] == 1:
+				return gs, doubleOne(&gs, 1)
+			case d[0] == 1 || d[1] == 1:
+				gs.endTurn(0)
+				return gs, true
+			case d[0] == d[1]:
+				return gs, double(&gs, d[0])
+			}
+			gs.thisTurn += d[0] + d[1]
+			return gs, false
+		}
+	}
+}
+
+var pigRules = &ruleset{
+	name:       "Pig",
+	target:     win,
+	maxDice:    1,
+	roll:       func(int) gameAction { return fromAction(roll) },
+	stay:       fromAction(stay),
+	family:     holdAt,
+	familyName: "staying at k",
+}
+
+var twoDicePigRules = &ruleset{
+	name:    "Two-Dice Pig",
+	target:  win,
+	maxDice: 2,
+	roll: twoDiceRoll(
+		func(gs *gameState, _ int) bool {
+			gs.scores[gs.current] = 0
+			gs.endTurn(0)
+			return true
+		},
+		func(gs *gameState, die int) bool {
+			gs.thisTurn += 2 * die
+			return false
+		}),
+	stay:       stayAction,
+	family:     holdAt,
+	familyName: "staying at k",
+}
+
+var bigPigRules = &ruleset{
+	name:    "Big Pig",
+	target:  win,
+	maxDice: 2,
+	roll: twoDiceRoll(
+		func(gs *gameState, _ int) bool {
+			gs.thisTurn += 25
+			return false
+		},
+		func(gs *gameState, die int) bool {
+			gs.thisTurn += 2 * (die + die)
+			return false
+		}),
+	stay:       stayAction,
+	family:     holdAt,
+	familyName: "staying at k",
+}
+
+var hogRules = &ruleset{
+	name:    "Hog",
+	target:  win,
+	maxDice: 10,
+	roll: func(n int) gameAction {
+		return func(gs gameState, rng *rand.Rand) (gameState, bool) {
+			gs = gs.clone()
+			sum := 0
+			for _, d := range rollDice(rng, n) {
+				if d == 1 {
+					sum = 0
+					break
+				}
+				sum += d
+			}
+			gs.endTurn(sum)
+			return gs, true
+		}
+	},
+	stay:       stayAction,
+	family:     hogDice,
+	familyName: "rolling k dice",
+	maxK:       10,
+}
+
+// rulesets lists the known rulesets by lower-case name.
+var rulesets = map[string]*ruleset{
+	"pig":        pigRules,
+	"twodicepig": twoDicePigRules,
+	"bigpig":     bigPigRules,
+	"hog":        hogRules,
+}
+
+// holdAt returns a strategy that rolls as many dice as allowed until
+// thisTurn is at least k or would win the game, then stays. It always rolls
+// at least once, so that every turn scores or loses something.
+func holdAt(k int) gameStrategy {
+	return func(r *ruleset, gs gameState) gameAction {
+		if gs.thisTurn > 0 && (gs.thisTurn >= k || gs.scores[gs.current]+gs.thisTurn >= r.target) {
+			return r.stay
+		}
+		return r.roll(r.maxDice)
+	}
+}
+
+// hogDice returns a strategy that always rolls n dice, or as many as
+// allowed if that is fewer, and at least one.
+func hogDice(n int) gameStrategy {
+	return func(r *ruleset, gs gameState) gameAction {
+		return r.roll(max(1, min(n, r.maxDice)))
+	}
+}
+
+// playGame simulates a game under r and returns the index of the winner.
+// The first player is chosen at random.
+func playGame(r *ruleset, strategies []gameStrategy, rng *rand.Rand) int {
+	gs := gameState{scores: make([]int, len(strategies)), current: rng.Intn(len(strategies))}
+	for {
+		if gs.scores[gs.current]+gs.thisTurn >= r.target {
+			return gs.current
+		}
+		mover := gs.current
+		var turnIsOver bool
+		gs, turnIsOver = strategies[mover](r, gs)(gs, rng)
+		if turnIsOver && gs.scores[mover] >= r.target {
+			return mover
+		}
+	}
+}
+
+// DiceGame plays games of a variant ("pig", "twodicepig", "bigpig" or
+// "hog") to target points (the variant's default if 0) between players
+// using the variant's usual strategies with the parameters ks, and prints
+// the wins of each.
+func DiceGame(variant string, target, games int, seed int64, ks ...int) error {
+	base, ok := rulesets[strings.ToLower(variant)]
+	if !ok {
+		names := make([]string, 0, len(rulesets))
+		for name := range rulesets {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		return fmt.Errorf("unknown variant %q, want one of %s", variant, strings.Join(names, ", "))
+	}
+	if len(ks) < 2 {
+		return fmt.Errorf("need at least 2 players, have %d", len(ks))
+	}
+	for _, k := range ks {
+		if k < 1 || base.maxK > 0 && k > base.maxK {
+			if base.maxK > 0 {
+				return fmt.Errorf("%s: k must be between 1 and %d, got %d", base.name, base.maxK, k)
+			}
+			return fmt.Errorf("%s: k must be at least 1, got %d", base.name, k)
+		}
+	}
+	r := *base
+	if target > 0 {
+		r.target = target
+	}
+
+	strategies := make([]gameStrategy, len(ks))
+	for p, k := range ks {
+		strategies[p] = r.family(k)
+	}
+	wins := make([]int, len(ks))
+	rng := rand.New(rand.NewSource(seed))
+	for g := 0; g < games; g++ {
+		wins[playGame(&r, strategies, rng)]++
+	}
+
+	fmt.Printf("%s to %d, %d games\n", r.name, r.target, games)
+	for p, k := range ks {
+		fmt.Printf("Wins %s =% 4d: %s\n", r.familyName, k, ratioString(wins[p], games-wins[p]))
+	}
+	return nil
+}
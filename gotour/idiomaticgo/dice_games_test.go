@@ -0,0 +1,34 @@
+package idiomaticgo
+
+import (
+	"math/rand"
+	"testing"
+)
+
+func TestDiceGameRejectsBadK(t *testing.T) {
+	for _, tt := range []struct {
+		variant string
+		ks      []int
+	}{
+		{"pig", []int{0, 0}},
+		{"bigpig", []int{20, -1}},
+		{"hog", []int{0, 5}},
+		{"hog", []int{5, 20}},
+	} {
+		if err := DiceGame(tt.variant, 0, 10, 1, tt.ks...); err == nil {
+			t.Errorf("DiceGame(%q, %v) succeeded", tt.variant, tt.ks)
+		}
+	}
+}
+
+func TestStrategiesEndTheirTurns(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	for _, r := range []*ruleset{pigRules, twoDicePigRules, bigPigRules, hogRules} {
+		// With k = 0 both strategies would, without the lower bound, stay on
+		// or roll nothing forever and playGame would never return.
+		strategies := []gameStrategy{r.family(0), r.family(1)}
+		for g := 0; g < 100; g++ {
+			playGame(r, strategies, rng)
+		}
+	}
+}
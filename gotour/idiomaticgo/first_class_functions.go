@@ -34,7 +34,8 @@ In Go, functions can be passed around just like any other value. A function's ty
 describes the types of its arguments and return values.
 
 The action type is a function that takes a score and returns the resulting score and whether
-the current turn is over.
+the current turn is over. It also takes the random number generator used to roll the die, so
+that whoever plays the game decides where the randomness comes from.
 
 If the turn is over, the player and opponent fields in the resulting score should be swapped,
 as it is now the other player's turn.
@@ -76,7 +77,8 @@ Simulating a tournament
 =======================
 
 The roundRobin function simulates a tournament and tallies wins. Each strategy plays
-each other strategy gamesPerSeries times.
+each other strategy gamesPerSeries times. All the games draw from the one random
+number generator passed to roundRobin, rather than each making its own.
 
 Variadic function declarations
 ==============================
@@ -110,14 +112,15 @@ type score struct {
 	player, opponent, thisTurn int
 }
 
-// An action transitions stochastically to a resulting score.
-type action func(current score) (result score, turnIsOver bool)
+// An action transitions stochastically to a resulting score,
+// drawing random numbers from rng.
+type action func(current score, rng *rand.Rand) (result score, turnIsOver bool)
 
 // roll returns the (result, turnIsOver) outcome of simulating a die roll.
 // If the roll value is 1, then thisTurn score is abandoned, and the players'
 // roles swap.  Otherwise, the roll value is added to thisTurn.
-func roll(s score) (score, bool) {
-	outcome := rand.Intn(6) + 1 // A random int in [1, 6]
+func roll(s score, rng *rand.Rand) (score, bool) {
+	outcome := rng.Intn(6) + 1 // A random int in [1, 6]
 	if outcome == 1 {
 		return score{s.opponent, s.player, 0}, true
 	}
@@ -126,7 +129,7 @@ func roll(s score) (score, bool) {
 
 // stay returns the (result, turnIsOver) outcome of staying.
 // thisTurn score is added to the player's score, and the players' roles swap.
-func stay(s score) (score, bool) {
+func stay(s score, rng *rand.Rand) (score, bool) {
 	return score{s.opponent, s.player + s.thisTurn, 0}, true
 }
 
@@ -143,15 +146,16 @@ func stayAtK(k int) strategy {
 	}
 }
 
-// play simulates a Pig game and returns the winner (0 or 1).
-func play(strategy0, strategy1 strategy) int {
+// playWith simulates a Pig game, drawing all random numbers from rng, and
+// returns the winner (0 or 1).
+func playWith(rng *rand.Rand, strategy0, strategy1 strategy) int {
 	strategies := []strategy{strategy0, strategy1}
 	var s score
 	var turnIsOver bool
-	currentPlayer := rand.Intn(2) // Randomly decide who plays first
+	currentPlayer := rng.Intn(2) // Randomly decide who plays first
 	for s.player+s.thisTurn < win {
 		action := strategies[currentPlayer](s)
-		s, turnIsOver = action(s)
+		s, turnIsOver = action(s, rng)
 		if turnIsOver {
 			currentPlayer = (currentPlayer + 1) % 2
 		}
@@ -159,13 +163,14 @@ func play(strategy0, strategy1 strategy) int {
 	return currentPlayer
 }
 
-// roundRobin simulates a series of games between every pair of strategies.
-func roundRobin(strategies []strategy) ([]int, int) {
+// roundRobin simulates a series of games between every pair of strategies,
+// drawing all random numbers from rng.
+func roundRobin(strategies []strategy, rng *rand.Rand) ([]int, int) {
 	wins := make([]int, len(strategies))
 	for i := 0; i < len(strategies); i++ {
 		for j := i + 1; j < len(strategies); j++ {
 			for k := 0; k < gamesPerSeries; k++ {
-				winner := play(strategies[i], strategies[j])
+				winner := playWith(rng, strategies[i], strategies[j])
 				if winner == 0 {
 					wins[i]++
 				} else {
@@ -202,7 +207,7 @@ func PigSimulation() {
 	for k := range strategies {
 		strategies[k] = stayAtK(k + 1)
 	}
-	wins, games := roundRobin(strategies)
+	wins, games := roundRobin(strategies, rand.New(rand.NewSource(rand.Int63())))
 
 	for k := range strategies {
 		fmt.Printf("Wins, losses staying at k =% 4d: %s\n",
@@ -0,0 +1,239 @@
+package idiomaticgo
+
+/*
+Poll history
+============
+
+Besides the last State of each URL, the monitor goroutine keeps the most
+recent polls of every URL in a fixed-size ring buffer. Each sample records
+when the poll was made, how long it took, the HTTP status code and, for
+failed polls, a coarse class of error ("dns", "timeout", "connection", ...).
+Old samples are overwritten, so memory use does not grow with uptime.
+
+From the history the monitor computes, per URL:
+
+    uptime over the last hour and the last day
+    the 50th, 95th and 99th percentile latency of successful polls
+    whether the service level objective (SLO) is breached: uptime over the
+    last day below sloUptime, or 95th percentile latency above sloLatency
+
+The history is owned by the monitor goroutine like urlStatus. Stats, SaveHistory
+and LoadHistory send a function on the monitor's history channel, and the
+monitor goroutine runs it with the history map; the map is never shared.
+SaveHistory writes the history as JSON so that a restarted program can pick up
+where the previous one stopped.
+*/
+
+import (
+	"encoding/json"
+	"errors"
+	"net"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+const (
+	historySize = 2048            // samples kept per URL
+	sloUptime   = 0.99            // target uptime over 24 hours
+	sloLatency  = 1 * time.Second // target 95th percentile latency
+)
+
+// A sample is the outcome of a single poll.
+type sample struct {
+	Time     time.Time     `json:"time"`
+	Latency  time.Duration `json:"latency"`
+	Code     int           `json:"code,omitempty"`
+	ErrClass string        `json:"error,omitempty"`
+}
+
+// pollUp reports whether a poll that got the HTTP status code, or 0 if it
+// failed, counts as up: the server answered, and not with a server error.
+// The alerts, the url_up metric and the uptime statistics all use it.
+func pollUp(code int) bool {
+	return code > 0 && code < 500
+}
+
+// up reports whether the poll counts as up.
+func (s sample) up() bool {
+	return pollUp(s.Code)
+}
+
+// A ring holds the last historySize samples of a URL.
+type ring struct {
+	buf  []sample
+	next int // index of the slot to overwrite once buf is full
+}
+
+func (r *ring) add(s sample) {
+	if len(r.buf) < historySize {
+		r.buf = append(r.buf, s)
+		return
+	}
+	r.buf[r.next] = s
+	r.next = (r.next + 1) % historySize
+}
+
+// samples returns the samples in the order they were added.
+func (r *ring) samples() []sample {
+	return append(append([]sample(nil), r.buf[r.next:]...), r.buf[:r.next]...)
+}
+
+// Stats summarizes the poll history of a URL.
+type Stats struct {
+	URL         string        `json:"url"`
+	Samples     int           `json:"samples"`
+	Uptime1h    float64       `json:"uptime_1h"`  // percent
+	Uptime24h   float64       `json:"uptime_24h"` // percent
+	P50         time.Duration `json:"p50_ns"`
+	P95         time.Duration `json:"p95_ns"`
+	P99         time.Duration `json:"p99_ns"`
+	SLOBreached bool          `json:"slo_breached"`
+}
+
+// newStats computes the Stats of url from its samples as of now.
+func newStats(url string, samples []sample, now time.Time) Stats {
+	st := Stats{URL: url, Samples: len(samples)}
+	var latencies []time.Duration
+	var n1, up1, n24, up24 int
+	for _, s := range samples {
+		age := now.Sub(s.Time)
+		if age > 24*time.Hour {
+			continue
+		}
+		n24++
+		if s.up() {
+			up24++
+			latencies = append(latencies, s.Latency)
+		}
+		if age <= time.Hour {
+			n1++
+			if s.up() {
+				up1++
+			}
+		}
+	}
+	st.Uptime1h = percent(up1, n1)
+	st.Uptime24h = percent(up24, n24)
+	sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+	st.P50 = percentile(latencies, 50)
+	st.P95 = percentile(latencies, 95)
+	st.P99 = percentile(latencies, 99)
+	st.SLOBreached = n24 > 0 && (st.Uptime24h < 100*sloUptime || st.P95 > sloLatency)
+	return st
+}
+
+// percent returns 100*n/total, or 100 if there is nothing to count.
+func percent(n, total int) float64 {
+	if total == 0 {
+		return 100
+	}
+	return 100 * float64(n) / float64(total)
+}
+
+// percentile returns the nearest-rank pth percentile of the sorted slice d.
+func percentile(d []time.Duration, p int) time.Duration {
+	if len(d) == 0 {
+		return 0
+	}
+	rank := (p*len(d) + 99) / 100 // ceil(p/100 * len(d))
+	return d[max(rank, 1)-1]
+}
+
+// errorClass returns a coarse description of a poll error.
+func errorClass(err error) string {
+	var dnsErr *net.DNSError
+	var netErr net.Error
+	var opErr *net.OpError
+	switch {
+	case err == nil:
+		return ""
+	case errors.As(err, &dnsErr):
+		return "dns"
+	case errors.As(err, &netErr) && netErr.Timeout():
+		return "timeout"
+	case errors.As(err, &opErr):
+		return "connection"
+	}
+	return "other"
+}
+
+// Stats asks the monitor goroutine for the Stats of every URL, sorted by URL.
+func (m *Monitor) Stats() []Stats {
+	var stats []Stats
+	m.withHistory(func(h map[string]*ring) {
+		now := time.Now()
+		for url, r := range h {
+			stats = append(stats, newStats(url, r.samples(), now))
+		}
+	})
+	sort.Slice(stats, func(i, j int) bool { return stats[i].URL < stats[j].URL })
+	return stats
+}
+
+// SaveHistory writes the poll history to the file name.
+func (m *Monitor) SaveHistory(name string) error {
+	saved := make(map[string][]sample)
+	m.withHistory(func(h map[string]*ring) {
+		for url, r := range h {
+			saved[url] = r.samples()
+		}
+	})
+	data, err := json.Marshal(saved)
+	if err != nil {
+		return err
+	}
+	// Write to a temporary file first so a crash never leaves a torn file.
+	tmp, err := os.CreateTemp(filepath.Dir(name), filepath.Base(name)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), name)
+}
+
+// LoadHistory adds the poll history saved in the file name to the
+// monitor's history. The samples are merged by time with any recorded
+// since the monitor started, and only the latest historySize are kept.
+func (m *Monitor) LoadHistory(name string) error {
+	data, err := os.ReadFile(name)
+	if err != nil {
+		return err
+	}
+	var saved map[string][]sample
+	if err := json.Unmarshal(data, &saved); err != nil {
+		return err
+	}
+	m.withHistory(func(h map[string]*ring) {
+		for url, samples := range saved {
+			if r := h[url]; r != nil {
+				samples = append(samples, r.samples()...)
+			}
+			sort.SliceStable(samples, func(i, j int) bool { return samples[i].Time.Before(samples[j].Time) })
+			r := new(ring)
+			for _, s := range samples {
+				r.add(s)
+			}
+			h[url] = r
+		}
+	})
+	return nil
+}
+
+// withHistory runs f in the monitor goroutine and waits for it to finish.
+func (m *Monitor) withHistory(f func(map[string]*ring)) {
+	done := make(chan bool)
+	m.history <- func(h map[string]*ring) {
+		f(h)
+		done <- true
+	}
+	<-done
+}
@@ -0,0 +1,96 @@
+package idiomaticgo
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestLoadHistoryMergesByTime(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "history.json")
+	saved := NewMonitor(time.Hour)
+	defer saved.Stop()
+	for _, at := range []time.Duration{0, 2 * time.Minute, 4 * time.Minute} {
+		saved.Updates() <- upState(at)
+	}
+	if err := saved.SaveHistory(name); err != nil {
+		t.Fatal(err)
+	}
+
+	m := NewMonitor(time.Hour)
+	defer m.Stop()
+	for _, at := range []time.Duration{time.Minute, 5 * time.Minute} {
+		m.Updates() <- upState(at)
+	}
+	if err := m.LoadHistory(name); err != nil {
+		t.Fatal(err)
+	}
+	var got []time.Duration
+	m.withHistory(func(h map[string]*ring) {
+		for _, s := range h[testURL].samples() {
+			got = append(got, s.Time.Sub(epoch))
+		}
+	})
+	want := []time.Duration{0, time.Minute, 2 * time.Minute, 4 * time.Minute, 5 * time.Minute}
+	if len(got) != len(want) {
+		t.Fatalf("got samples at %v; want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("got samples at %v; want %v", got, want)
+		}
+	}
+}
+
+func TestServerErrorIsDown(t *testing.T) {
+	now := epoch.Add(time.Hour)
+	samples := []sample{
+		{Time: epoch.Add(10 * time.Minute), Code: 200},
+		{Time: epoch.Add(20 * time.Minute), Code: 503},
+		{Time: epoch.Add(30 * time.Minute), ErrClass: "timeout"},
+		{Time: epoch.Add(40 * time.Minute), Code: 404},
+	}
+	if st := newStats(testURL, samples, now); st.Uptime1h != 50 {
+		t.Errorf("uptime = %v%%; want 50%%", st.Uptime1h)
+	}
+	for _, s := range samples {
+		st := State{code: s.Code, errClass: s.ErrClass}
+		if st.up() != s.up() {
+			t.Errorf("State and sample disagree on whether %+v is up", s)
+		}
+	}
+}
+
+func TestPollServerError(t *testing.T) {
+	code := http.StatusServiceUnavailable
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(code)
+	}))
+	defer ts.Close()
+
+	b := &Breaker{Threshold: 2, Cooldown: time.Hour}
+	r := NewResource(ts.URL, ConstantBackoff{Interval: time.Minute}, b)
+	for i := 1; i <= 2; i++ {
+		if s := r.Poll(); s != "503 Service Unavailable" {
+			t.Fatalf("poll %d = %q; want 503 Service Unavailable", i, s)
+		}
+		if r.errCount != i {
+			t.Errorf("after poll %d errCount = %d; want %d", i, r.errCount, i)
+		}
+	}
+	if b.State() != BreakerOpen {
+		t.Errorf("breaker is %s after two 503s; want open", b.State())
+	}
+	if d := r.backoff.Delay(r.errCount, 0); d != time.Minute {
+		t.Errorf("back-off after 503s = %v; want 1m", d)
+	}
+
+	code = http.StatusNotFound
+	b.state = BreakerHalfOpen
+	r.Poll()
+	if r.errCount != 0 || b.State() != BreakerClosed {
+		t.Errorf("after a 404 errCount = %d, breaker %s; want 0, closed", r.errCount, b.State())
+	}
+}
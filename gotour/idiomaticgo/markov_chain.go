@@ -41,18 +41,22 @@ limit. (The word limit is necessary as the chain table may contain cycles.)
 Our version of this program reads text from standard input, parsing it into a
 Markov chain, and writes generated text to standard output.
 The prefix and output lengths can be specified using the -prefix and -words
-flags on the command-line.
+flags on the command-line. The train and generate subcommands split the two
+steps: train saves the chain built from a corpus to a file, and generate
+loads it to write text.
 */
 package idiomaticgo
 
 import (
-	"bufio"
 	"flag"
 	"fmt"
 	"io"
-	"math/rand"
+	"log"
 	"os"
+	"slices"
 	"strings"
+
+	"gotour/markov"
 )
 
 /*
@@ -91,6 +95,12 @@ Prefix               Map key
 []string{"", "I"}    " I"
 []string{"I", "am"}  "I am"
 
+The Chain below does not store this map itself. It is built on markov.Chain[string], the
+generic version of the same algorithm (see package markov), which numbers the words, keys
+each prefix by the numbers of its words, and counts how often each distinct suffix occurred
+rather than listing every occurrence. "I am" above, for instance, has the suffixes
+{"a": 1, "not": 1}.
+
 */
 
 /*
@@ -137,16 +147,18 @@ func (p Prefix) Shift(word string) {
 
 The Chain struct
 ================
-The complete state of the chain table consists of the table itself and the word length
-of the prefixes. The Chain struct stores this data.
+The complete state of the chain consists of the table of prefixes and suffixes, with the
+word length of the prefixes, and of the way text is split into words. The Chain struct
+stores the table as a markov.Chain[string] and the splitting as a markov.Tokenizer.
 
 */
-// Chain contains a map ("chain") of prefixes to a list of suffixes.
-// A prefix is a string of prefixLen words joined with spaces.
-// A suffix is a single word. A prefix can have multiple suffixes.
+// Chain is a Markov chain of the tokens of text, by default words. A prefix
+// is prefixLen tokens and a suffix is a single token. A prefix can have
+// multiple suffixes, each counted by the number of times it followed the
+// prefix.
 type Chain struct {
-	chain     map[string][]string
-	prefixLen int
+	chain     *markov.Chain[string]
+	tokenizer markov.Tokenizer
 }
 
 /*
@@ -169,7 +181,23 @@ like this means we could easily move it into its own package at some later date.
 
 // NewChain returns a new Chain with prefixes of prefixLen words.
 func NewChain(prefixLen int) *Chain {
-	return &Chain{make(map[string][]string), prefixLen}
+	return NewTokenChain(prefixLen, markov.Word{})
+}
+
+// NewTokenChain returns a new Chain with prefixes of prefixLen tokens, which
+// splits text into tokens and joins them with t.
+func NewTokenChain(prefixLen int, t markov.Tokenizer) *Chain {
+	return &Chain{markov.NewChain[string](prefixLen), t}
+}
+
+// PrefixLen returns the number of tokens in the prefixes of c.
+func (c *Chain) PrefixLen() int {
+	return c.chain.PrefixLen()
+}
+
+// Tokenizer returns the tokenizer of c.
+func (c *Chain) Tokenizer() markov.Tokenizer {
+	return c.tokenizer
 }
 
 /*
@@ -181,50 +209,26 @@ The Build method reads text from an io.Reader and parses it into prefixes and su
 are stored in the Chain.
 
 The io.Reader is an interface type that is widely used by the standard library and other Go
-code. Our code uses the fmt.Fscan function, which reads space-separated values from an io.Reader.
+code. The original version of this program read words with the fmt.Fscan function, which reads
+space-separated values from an io.Reader, and stopped at io.EOF (end of file) or any other
+read error.
 
-The Build method returns once the Reader's Read method returns io.EOF (end of file) or some
-other read error occurs.
+Scanning tokens
+---------------
 
-Buffering the input
--------------------
-
-This function does many small reads, which can be inefficient for some Readers. For efficiency
-we wrap the provided io.Reader with bufio.NewReader to create a new io.Reader that provides buffering.
-
-Scanning words
---------------
-
-In our loop we read words from the Reader into a string variable s using fmt.Fscan. Since Fscan uses
-space to separate each input value, each call will yield just one word (including punctuation),
-which is exactly what we need.
-
-Fscan returns an error if it encounters a read error (io.EOF, for example) or if it can't scan the
-requested value (in our case, a single string). In either case we just want to stop scanning,
-so we break out of the loop.
+Our Chain splits text with its Tokenizer instead. A Tokenizer's Split method is a
+bufio.SplitFunc, so a bufio.Scanner does the reading: it buffers the input, which spares us
+many small reads, and returns one token at a time. The markov.Word tokenizer splits at white
+space just as fmt.Fscan does, so each token is one word (including punctuation). The
+markov.Char, markov.Punct and markov.NGram tokenizers split the same text into characters,
+into words and punctuation, or into runs of characters.
 
 Adding a prefix and suffix to the chain
 ---------------------------------------
 
-The word stored in s is a new suffix. We add the new prefix/suffix combination to the chain map
-by computing the map key with p.String and appending the suffix to the slice stored under that key.
-
-The built-in append function appends elements to a slice and allocates new storage when necessary.
- When the provided slice is nil, append allocates a new slice. This behavior conveniently ties
- in with the semantics of our map: retrieving an unset key returns the zero value of the value
- type and the zero value of []string is nil. When our program encounters a new prefix
- (yielding a nil value in the map) append will allocate a new slice.
-
-For more information about the append function and slices in general see the Slices: usage
-and internals article.
-
-Pushing the suffix onto the prefix
-----------------------------------
-
-Before reading the next word our algorithm requires us to drop the first word from
-the prefix and push the current suffix onto the prefix.
-
-When in this state
+Each token is a new suffix of the current prefix. The markov.Chain counts the pair, and
+then drops the first token from the prefix and pushes the suffix onto it, just as the Shift
+method of Prefix does:
 
 p == Prefix{"I", "am"}
 s == "not"
@@ -233,25 +237,30 @@ the new value for p would be
 
 p == Prefix{"am", "not"}
 
-This operation is also required during text generation so we put the code to perform
-this mutation of the slice inside a method on Prefix named Shift.
+The end of the text is not recorded: generated text runs on until it has enough words, as
+it always has. BuildLines is different: it treats every line as a text of its own, such as a
+name, and records where each ends, so that the generated text ends there too.
 
 */
 
 // Build reads text from the provided Reader and
 // parses it into prefixes and suffixes that are stored in Chain.
+// It stops at the first read error, as BuildText reports.
 func (c *Chain) Build(r io.Reader) {
-	br := bufio.NewReader(r)
-	p := NewPrefix(c.prefixLen)
-	for {
-		var s string
-		if _, err := fmt.Fscan(br, &s); err != nil {
-			break
-		}
-		key := p.String()
-		c.chain[key] = append(c.chain[key], s)
-		p.Shift(s)
-	}
+	c.BuildText(r)
+}
+
+// BuildText is like Build but returns the read error, if any.
+func (c *Chain) BuildText(r io.Reader) error {
+	tokens, err := markov.Tokens(r, c.tokenizer)
+	c.chain.BuildOpen(tokens)
+	return err()
+}
+
+// BuildLines adds every non-empty line read from r to c as a text of its own,
+// with its end, as for a list of names to be generated letter by letter.
+func (c *Chain) BuildLines(r io.Reader) error {
+	return markov.BuildLines(c.chain, r, c.tokenizer)
 }
 
 /*
@@ -268,47 +277,41 @@ Generate uses a conditional for loop to generate up to n words.
 Getting potential suffixes
 ===========================
 
-At each iteration of the loop we retrieve a list of potential suffixes for the current
-prefix. We access the chain map at key p.String() and assign its contents to choices.
+At each iteration of the loop we retrieve the table of potential suffixes for the current
+prefix.
 
-If len(choices) is zero we break out of the loop as there are no potential suffixes
-for that prefix. This test also works if the key isn't present in the
-map at all: in that case, choices will be nil and the length of a nil slice is zero.
+If there is none, or if the suffix picked is the end of a line added by BuildLines, we
+break out of the loop as there is nothing to follow that prefix.
 
 Choosing a suffix at random
 ===========================
 
-To choose a suffix we use the rand.Intn function. It returns a random integer up
-to (but not including) the provided value. Passing in len(choices) gives us a
-random index into the full length of the list.
+To choose a suffix we ask the table to pick one at random, with a probability proportional
+to its count. This is what picking a random element of a list holding every occurrence
+would do, without storing the occurrences. GenerateWith can also reshape the
+probabilities; Generate uses them as they are.
 
-We use that index to pick our new suffix, assign it to next and append it
-to the words slice.
+We assign the suffix to next and append it to the words slice.
 
 Next, we Shift the new suffix onto the prefix just as we did in the Build method.
 
 Returning the generated text
 ============================
 
-Before returning the generated text as a string, we use the strings.Join function
-to join the elements of the words slice together, separated by spaces.
+Before returning the generated text as a string, we use the Join method of the tokenizer to
+join the elements of the words slice together; markov.Word separates them by spaces, as
+strings.Join would.
 
 */
 
 // Generate returns a string of at most n words generated from Chain.
 func (c *Chain) Generate(n int) string {
-	p := make(Prefix, c.prefixLen)
-	var words []string
-	for i := 0; i < n; i++ {
-		choices := c.chain[p.String()]
-		if len(choices) == 0 {
-			break
-		}
-		next := choices[rand.Intn(len(choices))]
-		words = append(words, next)
-		p.Shift(next)
-	}
-	return strings.Join(words, " ")
+	return c.GenerateWith(n, GenerateOptions{})
+}
+
+// GenerateWith is like Generate but generates text as set by opts.
+func (c *Chain) GenerateWith(n int, opts GenerateOptions) string {
+	return c.tokenizer.Join(slices.Collect(c.Words(n, opts)))
 }
 
 func MarkovTextGenerator() {
@@ -318,6 +321,14 @@ func MarkovTextGenerator() {
 
 	flag.Parse() // Parse command-line flags.
 
+	// "train" and "generate" save the chain to a file and load it again.
+	if flag.NArg() > 0 {
+		if err := MarkovCommand(flag.Args(), os.Stdin, os.Stdout); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
 	c := NewChain(*prefixLen)     // Initialize a new Chain.
 	c.Build(os.Stdin)             // Build chains from standard input.
 	text := c.Generate(*numWords) // Generate text.
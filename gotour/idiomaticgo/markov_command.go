@@ -0,0 +1,294 @@
+package idiomaticgo
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"math/bits"
+	"math/rand"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+
+	"gotour/markov"
+)
+
+const markovUsage = `usage:
+	markov train [-prefix n] [-tokenizer name] [-n n] [-workers n] -o model [file|dir ...]
+	markov merge -o model model ...
+	markov stats [-prefix n,...] [-tokenizer name] [-n n] [-k k] [-workers n] -test file [file|dir ...]
+	markov generate [-words n] [-temperature t] [-topk k] [-topp p]
+		[-seed n] [-start text] [-sentence] -model model
+	markov tokens [-tokenizer name] [-n n] [-prefix n] [-lines] [-count n] [-max n] [-seed n] [file ...]`
+
+// MarkovCommand runs the markov command with the given arguments:
+//
+//	markov train [-prefix n] [-tokenizer name] [-n n] [-workers n] -o model [file|dir ...]
+//
+// builds a Chain of the tokens split by the named tokenizer ("word", "char",
+// "punct" or "ngram", with n characters per token) from the files and the
+// files in the directories, on workers goroutines (or from stdin if there are
+// none), and saves it to model, as JSON if its name ends in ".json".
+//
+//	markov merge -o model model ...
+//
+// merges models saved by train into one.
+//
+//	markov stats [-prefix n,...] [-tokenizer name] [-n n] [-k k] [-workers n] -test file [file|dir ...]
+//
+// builds a Chain with each of the prefix lengths from the files and the
+// files in the directories, and prints a table of their statistics and of
+// their perplexity on the held-out text in the test file.
+//
+//	markov generate [-words n] [-temperature t] [-topk k] [-topp p]
+//		[-seed n] [-start text] [-sentence] -model model
+//
+// loads a Chain saved by train and writes generated text to stdout as it is
+// generated, as described by GenerateOptions. A -seed other than 0 makes the
+// text reproducible. The -start text is split by the model's tokenizer.
+//
+//	markov tokens [-tokenizer name] [-n n] [-prefix n] [-lines] [-count n] [-max n] [-seed n] [file ...]
+//
+// builds a Chain of the tokens split by the named tokenizer from the files or
+// stdin, as train does but without saving it, and writes count generated
+// texts to stdout. With -lines, every line
+// is a sequence of its own, as for generating names letter by letter.
+func MarkovCommand(args []string, stdin io.Reader, stdout io.Writer) error {
+	if len(args) == 0 {
+		return errors.New(markovUsage)
+	}
+	switch args[0] {
+	case "train":
+		return markovTrain(args[1:], stdin)
+	case "merge":
+		return markovMerge(args[1:])
+	case "stats":
+		return markovStats(args[1:], stdout)
+	case "generate":
+		return markovGenerate(args[1:], stdout)
+	case "tokens":
+		return markovTokens(args[1:], stdin, stdout)
+	}
+	return fmt.Errorf("markov: unknown command %q\n%s", args[0], markovUsage)
+}
+
+func markovTrain(args []string, stdin io.Reader) error {
+	fs := flag.NewFlagSet("markov train", flag.ContinueOnError)
+	prefixLen := fs.Int("prefix", 2, "prefix length in tokens")
+	name := fs.String("tokenizer", "word", "tokenizer: word, char, punct or ngram")
+	n := fs.Int("n", 2, "characters per token for the ngram tokenizer")
+	model := fs.String("o", "", "file to save the model to")
+	workers := fs.Int("workers", 0, "files to read at once (0 for the number of CPUs)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *model == "" {
+		return errors.New("markov train: missing -o")
+	}
+	if *prefixLen < 1 {
+		return fmt.Errorf("markov train: invalid prefix length %d", *prefixLen)
+	}
+	t, err := markov.NewTokenizer(*name, *n)
+	if err != nil {
+		return fmt.Errorf("markov train: %w", err)
+	}
+
+	if fs.NArg() == 0 {
+		c := NewTokenChain(*prefixLen, t)
+		if err := c.BuildText(stdin); err != nil {
+			return err
+		}
+		return c.Save(*model)
+	}
+	names, err := corpusFiles(fs.Args())
+	if err != nil {
+		return err
+	}
+	c, err := Train(names, *prefixLen, t, *workers)
+	if err != nil {
+		return err
+	}
+	return c.Save(*model)
+}
+
+func markovMerge(args []string) error {
+	fs := flag.NewFlagSet("markov merge", flag.ContinueOnError)
+	model := fs.String("o", "", "file to save the merged model to")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *model == "" || fs.NArg() == 0 {
+		return errors.New("usage: markov merge -o model model ...")
+	}
+
+	var merged *Chain
+	for _, name := range fs.Args() {
+		c, err := LoadChain(name)
+		if err != nil {
+			return err
+		}
+		if merged == nil {
+			merged = c
+		} else if err := merged.Merge(c); err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+	}
+	return merged.Save(*model)
+}
+
+func markovStats(args []string, stdout io.Writer) error {
+	fs := flag.NewFlagSet("markov stats", flag.ContinueOnError)
+	prefixes := fs.String("prefix", "1,2,3", "comma-separated prefix lengths to compare")
+	name := fs.String("tokenizer", "word", "tokenizer: word, char, punct or ngram")
+	tokenLen := fs.Int("n", 2, "characters per token for the ngram tokenizer")
+	k := fs.Float64("k", 0.01, "add-k smoothing for the perplexity")
+	workers := fs.Int("workers", 0, "files to read at once (0 for the number of CPUs)")
+	test := fs.String("test", "", "held-out text to measure the perplexity on")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *test == "" || fs.NArg() == 0 {
+		return errors.New("usage: markov stats [-prefix n,...] [-tokenizer name] [-n n] [-k k] [-workers n] -test file [file|dir ...]")
+	}
+	t, err := markov.NewTokenizer(*name, *tokenLen)
+	if err != nil {
+		return fmt.Errorf("markov stats: %w", err)
+	}
+	var lengths []int
+	for _, f := range strings.Split(*prefixes, ",") {
+		n, err := strconv.Atoi(strings.TrimSpace(f))
+		if err != nil || n < 1 {
+			return fmt.Errorf("markov stats: invalid prefix length %q", f)
+		}
+		lengths = append(lengths, n)
+	}
+	heldOut, err := os.ReadFile(*test)
+	if err != nil {
+		return err
+	}
+	names, err := corpusFiles(fs.Args())
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(stdout, "%6s %9s %9s %9s %11s %8s %11s  %s\n",
+		"Prefix", "Prefixes", "Pairs", "Tokens", "Branching", "Entropy", "Perplexity", "Prefixes with 1, 2, 3-4, 5-8, ... suffixes")
+	for _, n := range lengths {
+		c, err := Train(names, n, t, *workers)
+		if err != nil {
+			return err
+		}
+		st := c.Stats()
+		pp, err := c.Perplexity(bytes.NewReader(heldOut), *k)
+		if err != nil {
+			return fmt.Errorf("%s: %w", *test, err)
+		}
+		fmt.Fprintf(stdout, "%6d %9d %9d %9d %11.2f %8.2f %11.1f  %v\n",
+			n, st.Prefixes, st.Pairs, st.Tokens, st.MeanBranching(), st.Entropy, pp, branchingBuckets(st.Branching))
+	}
+	return nil
+}
+
+// branchingBuckets groups a branching distribution by powers of two: the
+// numbers of prefixes with 1, 2, 3-4, 5-8, ... distinct suffixes.
+func branchingBuckets(branching map[int]int) []int {
+	var buckets []int
+	for b, count := range branching {
+		i := bits.Len(uint(b - 1)) // 1 -> 0, 2 -> 1, 3-4 -> 2, 5-8 -> 3, ...
+		for len(buckets) <= i {
+			buckets = append(buckets, 0)
+		}
+		buckets[i] += count
+	}
+	return buckets
+}
+
+func markovGenerate(args []string, stdout io.Writer) error {
+	fs := flag.NewFlagSet("markov generate", flag.ContinueOnError)
+	numWords := fs.Int("words", 100, "maximum number of words to print")
+	model := fs.String("model", "", "file to load the model from")
+	var opts GenerateOptions
+	fs.Float64Var(&opts.Temperature, "temperature", 1, "sharpen (< 1) or flatten (> 1) the suffix distribution")
+	fs.IntVar(&opts.TopK, "topk", 0, "pick only among the k most frequent suffixes (0 for all)")
+	fs.Float64Var(&opts.TopP, "topp", 0, "pick only among the most frequent suffixes covering this probability (0 for all)")
+	seed := fs.Int64("seed", 0, "seed for the random numbers (0 for a random seed)")
+	start := fs.String("start", "", "words to start the text with")
+	fs.BoolVar(&opts.StopAtSentence, "sentence", false, "stop at the end of the first sentence")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *model == "" {
+		return errors.New("markov generate: missing -model")
+	}
+	if opts.Temperature < 0 || opts.TopK < 0 || opts.TopP < 0 || opts.TopP > 1 {
+		return errors.New("markov generate: -temperature and -topk must not be negative, -topp must be between 0 and 1")
+	}
+
+	if *seed != 0 {
+		opts.Source = rand.NewSource(*seed)
+	}
+	c, err := LoadChain(*model)
+	if err != nil {
+		return err
+	}
+	tokens, _ := markov.Tokens(strings.NewReader(*start), c.Tokenizer())
+	opts.Start = slices.Collect(tokens)
+	return c.GenerateTo(stdout, *numWords, opts)
+}
+
+func markovTokens(args []string, stdin io.Reader, stdout io.Writer) error {
+	fs := flag.NewFlagSet("markov tokens", flag.ContinueOnError)
+	name := fs.String("tokenizer", "word", "tokenizer: word, char, punct or ngram")
+	n := fs.Int("n", 2, "characters per token for the ngram tokenizer")
+	prefixLen := fs.Int("prefix", 2, "prefix length in tokens")
+	lines := fs.Bool("lines", false, "treat every line as a separate sequence")
+	count := fs.Int("count", 1, "number of texts to generate")
+	maxTokens := fs.Int("max", 100, "maximum number of tokens per text")
+	seed := fs.Int64("seed", 0, "seed for the random numbers (0 for a random seed)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	t, err := markov.NewTokenizer(*name, *n)
+	if err != nil {
+		return fmt.Errorf("markov tokens: %w", err)
+	}
+	if *prefixLen < 1 {
+		return fmt.Errorf("markov tokens: invalid prefix length %d", *prefixLen)
+	}
+	if *seed == 0 {
+		*seed = rand.Int63()
+	}
+
+	c := NewTokenChain(*prefixLen, t)
+	build := c.BuildText
+	if *lines {
+		build = c.BuildLines
+	}
+	if fs.NArg() == 0 {
+		if err := build(stdin); err != nil {
+			return err
+		}
+	}
+	for _, name := range fs.Args() {
+		f, err := os.Open(name)
+		if err != nil {
+			return err
+		}
+		err = build(f)
+		f.Close()
+		if err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+	}
+
+	opts := GenerateOptions{Source: rand.NewSource(*seed)}
+	for i := 0; i < *count; i++ {
+		if err := c.GenerateTo(stdout, *maxTokens, opts); err != nil {
+			return err
+		}
+	}
+	return nil
+}
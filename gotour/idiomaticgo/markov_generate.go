@@ -0,0 +1,111 @@
+package idiomaticgo
+
+/*
+Reproducible and streaming generation
+=====================================
+
+Generate draws its random numbers from a source seeded at random, always starts
+from the empty prefix and builds the whole text in memory. GenerateOptions can
+change all three:
+
+    Source          the source of random numbers; with a source seeded by
+                    rand.NewSource(seed), the same chain always generates the
+                    same text
+    Start           words to begin the text with; the prefix is made of their
+                    last words, so the text carries on from them
+    StopAtSentence  stop after the first generated word that ends a sentence,
+                    such as "man!" or "said."
+
+GenerateOptions also carries the Temperature, TopK and TopP of markov.Options,
+which reshape the distribution of the suffixes; see package markov.
+
+Words returns the generated words as an iter.Seq, to be ranged over:
+
+	for w := range c.Words(100, opts) {
+		fmt.Println(w)
+	}
+
+Each word is generated only when the loop asks for the next one, and breaking
+out of the loop stops the generation. GenerateTo uses it to write the words to
+an io.Writer as they are generated, so the text never has to fit in memory. It
+joins each word to the one before with the chain's tokenizer, so characters
+are written without spaces between them.
+*/
+
+import (
+	"bufio"
+	"io"
+	"iter"
+	"math/rand"
+	"strings"
+	"unicode/utf8"
+
+	"gotour/markov"
+)
+
+// GenerateOptions control how a Chain generates text. The zero value picks
+// suffixes in proportion to their counts, from a randomly seeded source.
+type GenerateOptions struct {
+	Temperature float64 // sharpen (< 1) or flatten (> 1) the distribution; 0 means 1
+	TopK        int     // keep only the TopK most frequent suffixes; 0 keeps all
+	TopP        float64 // keep the most frequent suffixes covering TopP of the probability; 0 keeps all
+
+	Source         rand.Source // random numbers; nil means a randomly seeded source
+	Start          []string    // words to begin the text with, generation continues after them
+	StopAtSentence bool        // stop after a word ending a sentence
+}
+
+// options returns the options of o that reshape the distribution.
+func (o GenerateOptions) options() markov.Options {
+	return markov.Options{Temperature: o.Temperature, TopK: o.TopK, TopP: o.TopP}
+}
+
+// Words returns a sequence of the opts.Start words followed by at most n
+// generated words. Each iteration generates a new text, drawing on
+// opts.Source if it is set.
+func (c *Chain) Words(n int, opts GenerateOptions) iter.Seq[string] {
+	return func(yield func(string) bool) {
+		src := opts.Source
+		if src == nil {
+			src = rand.NewSource(rand.Int63())
+		}
+		for _, w := range opts.Start {
+			if !yield(w) {
+				return
+			}
+		}
+		for next := range c.chain.GenerateWith(opts.Start, n, rand.New(src), opts.options()) {
+			if !yield(next) || opts.StopAtSentence && endsSentence(next) {
+				return
+			}
+		}
+	}
+}
+
+// GenerateTo writes the words of c.Words(n, opts) to w, joined by the
+// tokenizer of c and followed by a newline.
+func (c *Chain) GenerateTo(w io.Writer, n int, opts GenerateOptions) error {
+	bw := bufio.NewWriter(w)
+	var prev []string
+	for word := range c.Words(n, opts) {
+		// Join the word to the one before, to learn what goes between them.
+		s := c.tokenizer.Join(append(prev, word))
+		if len(prev) > 0 {
+			s = s[len(prev[0]):]
+		}
+		if _, err := bw.WriteString(s); err != nil {
+			return err
+		}
+		prev = append(prev[:0], word)
+	}
+	bw.WriteByte('\n')
+	return bw.Flush()
+}
+
+// endsSentence reports whether word ends a sentence: whether its last
+// character, ignoring closing quotes and brackets, is '.', '!' or '?'.
+func endsSentence(word string) bool {
+	word = strings.TrimRight(word, `"')]`+"”’")
+	r, _ := utf8.DecodeLastRuneInString(word)
+	return r == '.' || r == '!' || r == '?'
+}
@@ -0,0 +1,103 @@
+package idiomaticgo
+
+import (
+	"math/rand"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestWordsConcurrently(t *testing.T) {
+	c := NewChain(2)
+	c.Build(strings.NewReader(strings.Repeat("I am not a number! I am a free man! ", 20)))
+	var wg sync.WaitGroup
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for w := range c.Words(50, GenerateOptions{Source: rand.NewSource(int64(g))}) {
+				if w == "" {
+					t.Error("empty word")
+				}
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func TestWordsSeeded(t *testing.T) {
+	c := NewChain(1)
+	c.Build(strings.NewReader("a b a c a b a d"))
+	opts := GenerateOptions{Source: rand.NewSource(1), Start: []string{"a"}, StopAtSentence: true}
+	first := c.GenerateWith(20, opts)
+	opts.Source = rand.NewSource(1)
+	if again := c.GenerateWith(20, opts); again != first {
+		t.Errorf("the same seed generated %q and %q", first, again)
+	}
+	if !strings.HasPrefix(first, "a ") {
+		t.Errorf("%q does not start with the start word", first)
+	}
+}
+
+// baselineGenerate generates text as the Chain did before it counted its
+// suffixes: from a list of every suffix occurrence, picked with rand.Intn.
+func baselineGenerate(text string, prefixLen, n int, rng *rand.Rand) string {
+	chain := make(map[string][]string)
+	p := NewPrefix(prefixLen)
+	for _, w := range strings.Fields(text) {
+		key := p.String()
+		chain[key] = append(chain[key], w)
+		p.Shift(w)
+	}
+	p = NewPrefix(prefixLen)
+	var words []string
+	for i := 0; i < n; i++ {
+		choices := chain[p.String()]
+		if len(choices) == 0 {
+			break
+		}
+		next := choices[rng.Intn(len(choices))]
+		words = append(words, next)
+		p.Shift(next)
+	}
+	return strings.Join(words, " ")
+}
+
+func TestGenerateLength(t *testing.T) {
+	c := NewChain(1)
+	c.Build(strings.NewReader("x y x y"))
+	for seed := int64(0); seed < 1000; seed++ {
+		got := c.GenerateWith(10, GenerateOptions{Source: rand.NewSource(seed)})
+		if want := baselineGenerate("x y x y", 1, 10, rand.New(rand.NewSource(seed))); got != want {
+			t.Fatalf("seed %d: generated %q, want %q", seed, got, want)
+		}
+	}
+}
+
+func TestGenerateDistribution(t *testing.T) {
+	const (
+		text   = "a b a c a b a d b a c a b b a"
+		trials = 20000
+	)
+	for _, prefixLen := range []int{1, 2} {
+		c := NewChain(prefixLen)
+		c.Build(strings.NewReader(text))
+		got, want := make(map[string]int), make(map[string]int)
+		for seed := int64(0); seed < trials; seed++ {
+			got[c.GenerateWith(5, GenerateOptions{Temperature: 1, Source: rand.NewSource(seed)})]++
+			want[baselineGenerate(text, prefixLen, 5, rand.New(rand.NewSource(seed)))]++
+		}
+		for s := range want {
+			if _, ok := got[s]; !ok {
+				got[s] = 0
+			}
+		}
+		for s, n := range got {
+			// The standard error of a frequency is at most 0.5/sqrt(trials),
+			// about 0.0035; allow a little over four of them.
+			if d := float64(n-want[s]) / trials; d > 0.015 || d < -0.015 {
+				t.Errorf("prefix %d: %q generated %d times in %d, want about %d", prefixLen, s, n, trials, want[s])
+			}
+		}
+	}
+}
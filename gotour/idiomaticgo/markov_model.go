@@ -0,0 +1,485 @@
+package idiomaticgo
+
+/*
+Saving and loading chains
+=========================
+
+Building a Chain from a large corpus takes a while, and the corpus has to be
+read again on every run. Instead a Chain can be saved to a file once and
+loaded whenever text needs to be generated.
+
+Two formats are supported. JSON is easy to read and to process with other
+tools. It lists the transitions of the chain (see markov.Transition), the
+prefix of each without the padding at the start of the text:
+
+	{"version": 3, "prefixLen": 2, "tokenizer": "word", "transitions": [
+		{"prefix": [], "suffix": "I", "count": 1},
+		{"prefix": ["I", "am"], "suffix": "a", "count": 1},
+		{"prefix": ["I", "am"], "suffix": "not", "count": 1},
+		{"prefix": ["a", "free"], "suffix": "man!", "count": 1},
+		...
+	]}
+
+A chain built by BuildLines also has transitions to the end of each line,
+such as {"prefix": ["b", "o"], "end": true, "count": 1} for "bob".
+
+The binary format is more compact. Tokens are stored once in a vocabulary,
+and prefixes and suffixes refer to them by index. Index 0 is the empty token:
+in a prefix it pads the start of the text, and as a suffix it is the end of
+a line. All integers are unsigned varints (see encoding/binary):
+
+	magic      "MKV\x00"
+	version    format version
+	prefixLen  tokens per prefix
+	tokenizer  the name of the tokenizer as a length and its bytes, and its
+	           number of characters per token (0 unless "ngram")
+	nwords     size of the vocabulary, followed by each token as a
+	           length and its bytes
+	nprefixes  number of prefixes, followed for each prefix by
+	           prefixLen token indexes, the number of suffixes and
+	           a token index and a count per suffix
+
+Both formats record the format version, the prefix length and the tokenizer
+(see markov.NewTokenizer), and loading checks that the model is consistent:
+every prefix has at most prefixLen tokens, with padding only at the start,
+every count is positive, no token is empty and, for the word tokenizer, no
+word contains white space. The suffixes of a prefix are saved in the order
+they first followed it, so a loaded chain generates the same text from the
+same seed as the chain that was saved.
+
+Versions 1 and 2 of both formats, which only had words, no end of text and
+keyed the prefixes of the JSON format by their words joined with spaces, can
+still be loaded. Version 1 listed every occurrence of a suffix instead of
+counting them.
+*/
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"unicode"
+
+	"gotour/markov"
+)
+
+const (
+	markovMagic   = "MKV\x00"
+	markovVersion = 3
+)
+
+// chainJSON is the JSON form of a Chain. Chain holds the prefixes of
+// versions 1 and 2: a map[string]map[string]int, or a map[string][]string in
+// version 1.
+type chainJSON struct {
+	Version     int              `json:"version"`
+	PrefixLen   int              `json:"prefixLen"`
+	Tokenizer   string           `json:"tokenizer,omitempty"`
+	N           int              `json:"n,omitempty"`
+	Transitions []transitionJSON `json:"transitions,omitempty"`
+	Chain       json.RawMessage  `json:"chain,omitempty"`
+}
+
+// transitionJSON is the JSON form of a markov.Transition[string].
+type transitionJSON struct {
+	Prefix []string `json:"prefix"`
+	Suffix string   `json:"suffix,omitempty"`
+	End    bool     `json:"end,omitempty"`
+	Count  int      `json:"count"`
+}
+
+// tokenizerName returns the name under which c's tokenizer is saved.
+func (c *Chain) tokenizerName() (string, int, error) {
+	name, n, ok := markov.TokenizerName(c.tokenizer)
+	if !ok {
+		return "", 0, fmt.Errorf("markov: cannot save a chain split by %T", c.tokenizer)
+	}
+	return name, n, nil
+}
+
+// prefixGroup is a prefix and its transitions.
+type prefixGroup struct {
+	prefix      []string
+	transitions []markov.Transition[string]
+}
+
+// groups returns the transitions of c grouped by prefix, in a fixed order,
+// and the sorted vocabulary of c, starting with the empty token.
+func (c *Chain) groups() ([]prefixGroup, []string, error) {
+	var groups []prefixGroup
+	vocabulary := map[string]bool{"": true}
+	for t := range c.chain.All() {
+		if !t.End && t.Suffix == "" || slices.Contains(t.Prefix, "") {
+			return nil, nil, errors.New("markov: empty token in chain")
+		}
+		if n := len(groups); n == 0 || !slices.Equal(groups[n-1].prefix, t.Prefix) {
+			groups = append(groups, prefixGroup{prefix: t.Prefix})
+			for _, w := range t.Prefix {
+				vocabulary[w] = true
+			}
+		}
+		g := &groups[len(groups)-1]
+		g.transitions = append(g.transitions, t)
+		vocabulary[t.Suffix] = true
+	}
+	words := make([]string, 0, len(vocabulary))
+	for w := range vocabulary {
+		words = append(words, w)
+	}
+	sort.Strings(words) // "" first
+	// Shorter prefixes are padded at the start, and sort before longer ones.
+	sort.Slice(groups, func(i, j int) bool {
+		a, b := groups[i].prefix, groups[j].prefix
+		if len(a) != len(b) {
+			return len(a) < len(b)
+		}
+		return slices.Compare(a, b) < 0
+	})
+	return groups, words, nil
+}
+
+// MarshalJSON implements json.Marshaler.
+func (c *Chain) MarshalJSON() ([]byte, error) {
+	name, n, err := c.tokenizerName()
+	if err != nil {
+		return nil, err
+	}
+	groups, _, err := c.groups()
+	if err != nil {
+		return nil, err
+	}
+	j := chainJSON{Version: markovVersion, PrefixLen: c.chain.PrefixLen(), Tokenizer: name, N: n}
+	for _, g := range groups {
+		for _, t := range g.transitions {
+			j.Transitions = append(j.Transitions, transitionJSON{append([]string{}, g.prefix...), t.Suffix, t.End, t.Count})
+		}
+	}
+	return json.Marshal(j)
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (c *Chain) UnmarshalJSON(data []byte) error {
+	var j chainJSON
+	if err := json.Unmarshal(data, &j); err != nil {
+		return err
+	}
+	if j.PrefixLen < 1 {
+		return fmt.Errorf("markov: invalid prefix length %d", j.PrefixLen)
+	}
+	t := markov.Tokenizer(markov.Word{})
+	if j.Version == markovVersion {
+		var err error
+		if t, err = markov.NewTokenizer(j.Tokenizer, j.N); err != nil {
+			return err
+		}
+	}
+	loaded := NewTokenChain(j.PrefixLen, t)
+	switch j.Version {
+	case 1:
+		var lists map[string][]string
+		if err := json.Unmarshal(j.Chain, &lists); err != nil {
+			return err
+		}
+		for key, suffixes := range lists {
+			if len(suffixes) == 0 {
+				return fmt.Errorf("markov: prefix %q has no suffixes", key)
+			}
+			for _, s := range suffixes {
+				if err := loaded.addKey(key, s, 1); err != nil {
+					return err
+				}
+			}
+		}
+	case 2:
+		var counts map[string]map[string]int
+		if err := json.Unmarshal(j.Chain, &counts); err != nil {
+			return err
+		}
+		for key, suffixes := range counts {
+			if len(suffixes) == 0 {
+				return fmt.Errorf("markov: prefix %q has no suffixes", key)
+			}
+			// Add the suffixes in a fixed order so that loading a model
+			// always gives the same tables.
+			words := make([]string, 0, len(suffixes))
+			for w := range suffixes {
+				words = append(words, w)
+			}
+			sort.Strings(words)
+			for _, w := range words {
+				if err := loaded.addKey(key, w, suffixes[w]); err != nil {
+					return err
+				}
+			}
+		}
+	case markovVersion:
+		for _, tj := range j.Transitions {
+			if err := loaded.add(markov.Transition[string]{Prefix: tj.Prefix, Suffix: tj.Suffix, End: tj.End, Count: tj.Count}); err != nil {
+				return err
+			}
+		}
+	default:
+		return fmt.Errorf("markov: unsupported format version %d", j.Version)
+	}
+	*c = *loaded
+	return nil
+}
+
+// addKey adds count occurrences of suffix after the prefix keyed by key as
+// in versions 1 and 2: prefixLen words joined by spaces, the empty word
+// padding the start of the text.
+func (c *Chain) addKey(key, suffix string, count int) error {
+	words := strings.Split(key, " ")
+	if len(words) != c.chain.PrefixLen() {
+		return fmt.Errorf("markov: prefix %q has %d words, want %d", key, len(words), c.chain.PrefixLen())
+	}
+	pad := 0
+	for pad < len(words) && words[pad] == "" {
+		pad++
+	}
+	return c.add(markov.Transition[string]{Prefix: words[pad:], Suffix: suffix, Count: count})
+}
+
+// add adds t to c after checking that it is valid in a saved model.
+func (c *Chain) add(t markov.Transition[string]) error {
+	_, isWord := c.tokenizer.(markov.Word)
+	valid := func(token string) bool {
+		return token != "" && !(isWord && strings.IndexFunc(token, unicode.IsSpace) >= 0)
+	}
+	for _, w := range t.Prefix {
+		if !valid(w) {
+			return fmt.Errorf("markov: invalid token %q in prefix %q", w, t.Prefix)
+		}
+	}
+	if !t.End && !valid(t.Suffix) {
+		return fmt.Errorf("markov: invalid suffix %q of prefix %q", t.Suffix, t.Prefix)
+	}
+	if t.Count <= 0 {
+		return fmt.Errorf("markov: suffix %q of prefix %q has count %d", t.Suffix, t.Prefix, t.Count)
+	}
+	return c.chain.Add(t)
+}
+
+// WriteTo writes c to w in the binary format.
+func (c *Chain) WriteTo(w io.Writer) (int64, error) {
+	name, n, err := c.tokenizerName()
+	if err != nil {
+		return 0, err
+	}
+	// Number the tokens in sorted order so that equal chains are saved as
+	// equal files. The empty token is always 0.
+	groups, words, err := c.groups()
+	if err != nil {
+		return 0, err
+	}
+	index := make(map[string]uint64, len(words))
+	for i, word := range words {
+		index[word] = uint64(i)
+	}
+
+	bw := &countingWriter{w: bufio.NewWriter(w)}
+	bw.write(markovMagic)
+	bw.uvarint(markovVersion)
+	bw.uvarint(uint64(c.chain.PrefixLen()))
+	bw.uvarint(uint64(len(name)))
+	bw.write(name)
+	bw.uvarint(uint64(n))
+	bw.uvarint(uint64(len(words)))
+	for _, word := range words {
+		bw.uvarint(uint64(len(word)))
+		bw.write(word)
+	}
+	bw.uvarint(uint64(len(groups)))
+	for _, g := range groups {
+		for range c.chain.PrefixLen() - len(g.prefix) {
+			bw.uvarint(0)
+		}
+		for _, word := range g.prefix {
+			bw.uvarint(index[word])
+		}
+		bw.uvarint(uint64(len(g.transitions)))
+		for _, t := range g.transitions {
+			if t.End {
+				bw.uvarint(0)
+			} else {
+				bw.uvarint(index[t.Suffix])
+			}
+			bw.uvarint(uint64(t.Count))
+		}
+	}
+	if bw.err == nil {
+		bw.err = bw.w.Flush()
+	}
+	return bw.n, bw.err
+}
+
+// countingWriter counts the bytes written to a bufio.Writer and remembers
+// the first error, so that WriteTo needs to check for errors only once.
+type countingWriter struct {
+	w   *bufio.Writer
+	n   int64
+	err error
+}
+
+func (cw *countingWriter) write(s string) {
+	if cw.err != nil {
+		return
+	}
+	n, err := cw.w.WriteString(s)
+	cw.n += int64(n)
+	cw.err = err
+}
+
+func (cw *countingWriter) uvarint(x uint64) {
+	var buf [binary.MaxVarintLen64]byte
+	cw.write(string(buf[:binary.PutUvarint(buf[:], x)]))
+}
+
+// ReadChain reads a Chain in the binary format from r.
+func ReadChain(r io.Reader) (*Chain, error) {
+	br := bufio.NewReader(r)
+	magic := make([]byte, len(markovMagic))
+	if _, err := io.ReadFull(br, magic); err != nil || string(magic) != markovMagic {
+		return nil, errors.New("markov: not a binary chain model")
+	}
+	var err error
+	next := func() uint64 {
+		if err != nil {
+			return 0
+		}
+		var x uint64
+		x, err = binary.ReadUvarint(br)
+		return x
+	}
+	str := func() string {
+		n := next()
+		if err == nil && n > 1<<20 {
+			err = fmt.Errorf("token length %d too large", n)
+		}
+		if err != nil {
+			return ""
+		}
+		buf := make([]byte, n)
+		_, err = io.ReadFull(br, buf)
+		return string(buf)
+	}
+
+	version := next()
+	if err == nil && (version < 1 || version > markovVersion) {
+		return nil, fmt.Errorf("markov: unsupported format version %d", version)
+	}
+	prefixLen := next()
+	t := markov.Tokenizer(markov.Word{})
+	if version == markovVersion {
+		name, n := str(), next()
+		if err == nil {
+			t, err = markov.NewTokenizer(name, int(min(n, math.MaxInt32)))
+		}
+	}
+	nwords := next()
+	if err != nil {
+		return nil, fmt.Errorf("markov: reading header: %w", err)
+	}
+	if prefixLen < 1 || prefixLen > 1<<16 {
+		return nil, fmt.Errorf("markov: invalid prefix length %d", prefixLen)
+	}
+	var words []string
+	for i := uint64(0); i < nwords && err == nil; i++ {
+		words = append(words, str())
+	}
+	word := func() (uint64, string) {
+		i := next()
+		if err == nil && i >= uint64(len(words)) {
+			err = fmt.Errorf("token index %d out of range", i)
+		}
+		if err != nil {
+			return 0, ""
+		}
+		return i, words[i]
+	}
+
+	c := NewTokenChain(int(prefixLen), t)
+	nprefixes := next()
+	p := make([]string, prefixLen)
+	for i := uint64(0); i < nprefixes && err == nil; i++ {
+		// Index 0 pads the start of the prefix.
+		pad := 0
+		for k := range p {
+			var w uint64
+			if w, p[k] = word(); w == 0 && pad == k {
+				pad++
+			}
+		}
+		n := next()
+		for k := uint64(0); k < n && err == nil; k++ {
+			w, s := word()
+			count := uint64(1)
+			if version > 1 {
+				count = next()
+			}
+			if err == nil && count > math.MaxInt32 {
+				err = fmt.Errorf("suffix %q of prefix %q has count %d", s, p, count)
+			}
+			if err == nil {
+				// In versions 1 and 2 the empty token is no suffix, and add
+				// rejects it.
+				end := w == 0 && version == markovVersion
+				err = c.add(markov.Transition[string]{Prefix: p[pad:], Suffix: s, End: end, Count: int(count)})
+			}
+		}
+	}
+	if err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return nil, fmt.Errorf("markov: %w", err)
+	}
+	return c, nil
+}
+
+// Save writes c to the file name, as JSON if name ends in ".json" and in
+// the binary format otherwise.
+func (c *Chain) Save(name string) error {
+	f, err := os.Create(name)
+	if err != nil {
+		return err
+	}
+	if filepath.Ext(name) == ".json" {
+		err = json.NewEncoder(f).Encode(c)
+	} else {
+		_, err = c.WriteTo(f)
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// LoadChain reads a Chain saved by Save from the file name.
+func LoadChain(name string) (*Chain, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	if filepath.Ext(name) == ".json" {
+		c := new(Chain)
+		if err := json.NewDecoder(f).Decode(c); err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+		return c, nil
+	}
+	c, err := ReadChain(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+	return c, nil
+}
@@ -0,0 +1,122 @@
+package idiomaticgo
+
+import (
+	"bytes"
+	"encoding/json"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"gotour/markov"
+)
+
+const corpus = "I am not a number! I am a free man! I am a man, not a number."
+
+// seeded returns a text of c generated from seed.
+func seeded(c *Chain, seed int64) string {
+	return c.GenerateWith(50, GenerateOptions{Source: rand.NewSource(seed)})
+}
+
+func TestChainRoundTrip(t *testing.T) {
+	for _, tok := range []markov.Tokenizer{markov.Word{}, markov.Char{}, markov.NGram{N: 3}} {
+		c := NewTokenChain(2, tok)
+		c.Build(strings.NewReader(corpus))
+		for _, ext := range []string{".bin", ".json"} {
+			name := filepath.Join(t.TempDir(), "model"+ext)
+			if err := c.Save(name); err != nil {
+				t.Fatal(err)
+			}
+			loaded, err := LoadChain(name)
+			if err != nil {
+				t.Fatalf("%T%s: %v", tok, ext, err)
+			}
+			if loaded.Tokenizer() != tok || loaded.PrefixLen() != 2 {
+				t.Errorf("%T%s: loaded a chain of %T with prefix length %d", tok, ext, loaded.Tokenizer(), loaded.PrefixLen())
+			}
+			for seed := int64(1); seed <= 5; seed++ {
+				if got, want := seeded(loaded, seed), seeded(c, seed); got != want {
+					t.Errorf("%T%s seed %d: loaded chain generated %q, want %q", tok, ext, seed, got, want)
+				}
+			}
+		}
+	}
+}
+
+func TestLoadVersion2(t *testing.T) {
+	data := `{"version": 2, "prefixLen": 2, "chain": {" ": {"I": 1}, " I": {"am": 1}, "I am": {"a": 1, "not": 1}}}`
+	var c Chain
+	if err := json.Unmarshal([]byte(data), &c); err != nil {
+		t.Fatal(err)
+	}
+	if count, total := c.chain.Count([]string{"I", "am"}, "not"); count != 1 || total != 2 {
+		t.Errorf("Count(I am, not) = %d, %d; want 1, 2", count, total)
+	}
+	if got := seeded(&c, 1); !strings.HasPrefix(got, "I am ") {
+		t.Errorf("version 2 chain generated %q", got)
+	}
+}
+
+func TestSaveCustomTokenizer(t *testing.T) {
+	c := NewTokenChain(1, splitter{})
+	c.Build(strings.NewReader(corpus))
+	var buf bytes.Buffer
+	if _, err := c.WriteTo(&buf); err == nil {
+		t.Error("WriteTo saved a chain with a custom tokenizer")
+	}
+}
+
+// splitter is a tokenizer that cannot be saved.
+type splitter struct{ markov.Word }
+
+func TestLinesRoundTrip(t *testing.T) {
+	c := NewTokenChain(2, markov.Char{})
+	if err := c.BuildLines(strings.NewReader("anna\nbob\nclara\ndora\n")); err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if _, err := c.WriteTo(&buf); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := ReadChain(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for seed := int64(1); seed <= 20; seed++ {
+		got, want := seeded(loaded, seed), seeded(c, seed)
+		if got != want {
+			t.Errorf("seed %d: loaded chain generated %q, want %q", seed, got, want)
+		}
+		if len(want) > 10 {
+			t.Errorf("seed %d: %q runs past the end of the names", seed, want)
+		}
+	}
+}
+
+func TestTrainMatchesBuild(t *testing.T) {
+	dir := t.TempDir()
+	texts := []string{corpus, "a free man is not a number", "I am what I am"}
+	var names []string
+	want := NewChain(2)
+	for i, text := range texts {
+		name := filepath.Join(dir, string(rune('a'+i)))
+		if err := os.WriteFile(name, []byte(text), 0644); err != nil {
+			t.Fatal(err)
+		}
+		names = append(names, name)
+		want.Build(strings.NewReader(text))
+	}
+	c, err := Train(names, 2, markov.Word{}, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for seed := int64(1); seed <= 5; seed++ {
+		if got, want := seeded(c, seed), seeded(want, seed); got != want {
+			t.Errorf("seed %d: trained chain generated %q, want %q", seed, got, want)
+		}
+	}
+	if err := c.Merge(NewTokenChain(2, markov.Char{})); err == nil {
+		t.Error("Merge accepted a chain with another tokenizer")
+	}
+}
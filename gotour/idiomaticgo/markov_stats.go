@@ -0,0 +1,64 @@
+package idiomaticgo
+
+/*
+Inspecting a chain
+==================
+
+Stats summarizes a Chain with the statistics of package markov:
+
+    prefixes   the number of distinct prefixes
+    pairs      the number of distinct prefix and suffix pairs
+    tokens     the number of words the chain was built from, plus one for
+               the end of each text
+    branching  how many prefixes have 1, 2, 3, ... distinct suffixes; a
+               chain whose prefixes mostly have a single suffix can only
+               repeat its corpus
+    entropy    the uncertainty of the next word given the prefix, in bits,
+               averaged over the prefixes and weighted by how often each
+               occurs; Entropy gives it for a single prefix
+
+Perplexity
+==========
+
+The perplexity of a model on a text measures how surprised the model is by
+it: it is 2 to the power of the average number of bits needed per word, or
+the number of equally likely words the model is choosing between on average.
+Measured on held-out text, text the chain was not built from, it shows how
+well the model generalizes. A chain gives probability 0 to every word it has
+not seen after a prefix, so Perplexity takes an add-k smoothing constant, as
+described in package markov.
+*/
+
+import (
+	"io"
+
+	"gotour/markov"
+)
+
+// Stats returns the statistics of c.
+func (c *Chain) Stats() markov.Stats {
+	return c.chain.Stats()
+}
+
+// Entropy returns the entropy in bits of the suffixes of p, or 0 if the chain
+// has no such prefix. The empty words of a prefix made by NewPrefix stand for
+// the start of the text.
+func (c *Chain) Entropy(p Prefix) float64 {
+	i := 0
+	for i < len(p) && p[i] == "" {
+		i++
+	}
+	return c.chain.Entropy(p[i:])
+}
+
+// Perplexity returns the perplexity of c on the text read from r, which is
+// split into tokens as by Build, with add-k smoothing. It returns +Inf if k
+// is 0 and the text has a token the chain cannot generate after its prefix.
+func (c *Chain) Perplexity(r io.Reader, k float64) (float64, error) {
+	tokens, err := markov.Tokens(r, c.tokenizer)
+	pp, perr := c.chain.Perplexity(tokens, k)
+	if err := err(); err != nil {
+		return 0, err
+	}
+	return pp, perr
+}
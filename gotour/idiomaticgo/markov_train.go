@@ -0,0 +1,156 @@
+package idiomaticgo
+
+/*
+Training in parallel
+====================
+
+Build reads one io.Reader after another, so training on a directory of large
+corpora uses a single core. Train builds a separate Chain for every file on a
+pool of worker goroutines, just as a tournament plays its series: a feeder
+goroutine sends the file names on a jobs channel, and each worker builds the
+chain of a whole file and sends it back on a results channel.
+
+Merging chains
+==============
+
+Merge adds the counts of one chain to another, prefix by prefix and suffix by
+suffix, with the Merge method of markov.Chain. Both chains must split text
+with the same tokenizer. Since Build starts every reader with the empty prefix, the chain built
+from a file does not depend on the files read before it, and merging the
+chains of several files gives the same counts as building one chain from all
+of them.
+
+The chains are merged in the order of the file names, even though the workers
+finish them in any order. Each suffix table then lists its suffixes in the
+order they first appeared, as it does after sequential training, so a seeded
+generation picks the same words from both. Chains that finish early wait in
+a map until it is their turn, and are released once they are merged.
+
+Merge also combines saved models: load them with LoadChain, merge them and
+save the result.
+*/
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime"
+
+	"gotour/markov"
+)
+
+// Merge adds the counts of other to c. Both must have the same prefix length
+// and tokenizer.
+func (c *Chain) Merge(other *Chain) error {
+	if c.tokenizer != other.tokenizer {
+		return fmt.Errorf("markov: cannot merge a chain of %T tokens into one of %T tokens", other.tokenizer, c.tokenizer)
+	}
+	return c.chain.Merge(other.chain)
+}
+
+// A trained chain is the result of building a chain from one file.
+type trained struct {
+	index int // of the file in the list of names
+	chain *Chain
+	err   error
+}
+
+// buildFile returns the Chain built from the file name.
+func buildFile(name string, prefixLen int, t markov.Tokenizer) (*Chain, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	c := NewTokenChain(prefixLen, t)
+	if err := c.BuildText(f); err != nil {
+		return nil, fmt.Errorf("%s: %v", name, err)
+	}
+	return c, nil
+}
+
+// Train builds a Chain from the files names, split into tokens by t, on
+// workers goroutines (runtime.NumCPU() if workers is 0 or less). The result
+// is the same as building the chain from each file in turn.
+func Train(names []string, prefixLen int, t markov.Tokenizer, workers int) (*Chain, error) {
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+	jobs, results := make(chan int), make(chan trained)
+	for w := 0; w < workers; w++ {
+		go func() {
+			for i := range jobs {
+				c, err := buildFile(names[i], prefixLen, t)
+				results <- trained{i, c, err}
+			}
+		}()
+	}
+	go func() {
+		for i := range names {
+			jobs <- i
+		}
+		close(jobs)
+	}()
+
+	c := NewTokenChain(prefixLen, t)
+	pending := make(map[int]trained)
+	var firstErr error
+	next := 0
+	for range names {
+		r := <-results
+		pending[r.index] = r
+		for r, ok := pending[next]; ok; r, ok = pending[next] {
+			delete(pending, next)
+			next++
+			if r.err != nil && firstErr == nil {
+				firstErr = r.err
+			}
+			if firstErr == nil {
+				c.Merge(r.chain)
+			}
+		}
+	}
+	if firstErr != nil {
+		return nil, firstErr
+	}
+	return c, nil
+}
+
+// TrainDir is like Train for all the regular files in the tree rooted at
+// dir, in the order filepath.WalkDir visits them.
+func TrainDir(dir string, prefixLen int, t markov.Tokenizer, workers int) (*Chain, error) {
+	names, err := corpusFiles([]string{dir})
+	if err != nil {
+		return nil, err
+	}
+	return Train(names, prefixLen, t, workers)
+}
+
+// corpusFiles returns paths, with every directory replaced by the regular
+// files in the tree rooted at it.
+func corpusFiles(paths []string) ([]string, error) {
+	var names []string
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, err
+		}
+		if !info.IsDir() {
+			names = append(names, path)
+			continue
+		}
+		var files []string
+		err = filepath.WalkDir(path, func(name string, d fs.DirEntry, err error) error {
+			if err == nil && d.Type().IsRegular() {
+				files = append(files, name)
+			}
+			return err
+		})
+		if err != nil {
+			return nil, err
+		}
+		names = append(names, files...)
+	}
+	return names, nil
+}
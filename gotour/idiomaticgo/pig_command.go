@@ -0,0 +1,133 @@
+package idiomaticgo
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"math/rand"
+	"runtime"
+	"strconv"
+)
+
+const pigUsage = `usage:
+	pig play [-k k] [-seed n]
+	pig simulate
+	pig optimal
+	pig tournament [-games n] [-workers n] [-seed n]
+	pig ratings [-format roundrobin|swiss|knockout] [-rounds n] [-games n] [-workers n] [-seed n]
+	pig dice [-variant name] [-target n] [-games n] [-seed n] k ...`
+
+// PigCommand runs the pig command with the given arguments:
+//
+//	pig play [-k k] [-seed n]
+//
+// plays an interactive game against stayAtK(k), reading moves from stdin
+// and writing to stdout (see PigInteractive). A -seed of 0 picks a random
+// game.
+//
+//	pig simulate
+//	pig optimal
+//	pig tournament [-games n] [-workers n] [-seed n]
+//	pig ratings [-format roundrobin|swiss|knockout] [-rounds n] [-games n] [-workers n] [-seed n]
+//	pig dice [-variant name] [-target n] [-games n] [-seed n] k ...
+//
+// run PigSimulation, PigOptimal, PigTournament, PigRatings and DiceGame,
+// which print their results to standard output.
+func PigCommand(args []string, stdin io.Reader, stdout io.Writer) error {
+	if len(args) == 0 {
+		return errors.New(pigUsage)
+	}
+	switch args[0] {
+	case "play":
+		return pigPlay(args[1:], stdin, stdout)
+	case "simulate":
+		PigSimulation()
+		return nil
+	case "optimal":
+		PigOptimal()
+		return nil
+	case "tournament":
+		return pigTournament(args[1:])
+	case "ratings":
+		return pigRatings(args[1:])
+	case "dice":
+		return pigDice(args[1:])
+	}
+	return fmt.Errorf("pig: unknown command %q\n%s", args[0], pigUsage)
+}
+
+func pigPlay(args []string, stdin io.Reader, stdout io.Writer) error {
+	fs := flag.NewFlagSet("pig play", flag.ContinueOnError)
+	k := fs.Int("k", 20, "the computer stays at `k` points")
+	seed := fs.Int64("seed", 0, "random seed; 0 picks one")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *k < 1 {
+		return fmt.Errorf("pig: -k must be at least 1, got %d", *k)
+	}
+	return PigInteractive(stdin, stdout, *k, pickSeed(*seed))
+}
+
+func pigTournament(args []string) error {
+	fs := flag.NewFlagSet("pig tournament", flag.ContinueOnError)
+	games := fs.Int("games", 1000, "games per series")
+	workers := fs.Int("workers", runtime.NumCPU(), "series to play at once")
+	seed := fs.Int64("seed", 0, "random seed; 0 picks one")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *games < 1 {
+		return fmt.Errorf("pig: -games must be at least 1, got %d", *games)
+	}
+	PigTournament(*games, *workers, pickSeed(*seed))
+	return nil
+}
+
+func pigRatings(args []string) error {
+	fs := flag.NewFlagSet("pig ratings", flag.ContinueOnError)
+	format := fs.String("format", "roundrobin", "tournament format: roundrobin, swiss or knockout")
+	rounds := fs.Int("rounds", 7, "Swiss rounds")
+	games := fs.Int("games", 100, "games per series")
+	workers := fs.Int("workers", runtime.NumCPU(), "series to play at once")
+	seed := fs.Int64("seed", 0, "random seed; 0 picks one")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *games < 1 {
+		return fmt.Errorf("pig: -games must be at least 1, got %d", *games)
+	}
+	return PigRatings(*format, *rounds, *games, *workers, pickSeed(*seed))
+}
+
+func pigDice(args []string) error {
+	fs := flag.NewFlagSet("pig dice", flag.ContinueOnError)
+	variant := fs.String("variant", "pig", "game: pig, twodicepig, bigpig or hog")
+	target := fs.Int("target", 0, "winning score; 0 for the variant's own")
+	games := fs.Int("games", 1000, "games to play")
+	seed := fs.Int64("seed", 0, "random seed; 0 picks one")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *games < 1 {
+		return fmt.Errorf("pig: -games must be at least 1, got %d", *games)
+	}
+	var ks []int
+	for _, arg := range fs.Args() {
+		k, err := strconv.Atoi(arg)
+		if err != nil {
+			return fmt.Errorf("pig: bad strategy parameter %q", arg)
+		}
+		ks = append(ks, k)
+	}
+	return DiceGame(*variant, *target, *games, pickSeed(*seed), ks...)
+}
+
+// pickSeed returns seed, or a random seed if it is 0.
+func pickSeed(seed int64) int64 {
+	if seed == 0 {
+		return rand.Int63()
+	}
+	return seed
+}
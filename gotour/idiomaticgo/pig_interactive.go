@@ -0,0 +1,173 @@
+package idiomaticgo
+
+/*
+Playing Pig yourself
+====================
+
+PigInteractive lets a person play Pig against a stayAtK strategy. It keeps the
+score in the same score type as the simulations and plays every move with the
+same roll and stay actions; only the choice between them is made by asking.
+
+The game reads commands from an io.Reader and writes to an io.Writer rather
+than using os.Stdin and os.Stdout directly. From a terminal we pass the
+standard streams; a test can pass a strings.Reader with a scripted game and a
+bytes.Buffer to check the output.
+
+Before each decision the game shows the probability of winning after rolling
+and after staying. They are estimated by quick simulation: the rest of the game
+is played oddsRollouts times, with the person playing on as stayAtK(oddsHoldAt).
+*/
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"strings"
+)
+
+const (
+	oddsRollouts = 500 // games simulated to estimate each odds
+	oddsHoldAt   = 20  // strategy assumed for the person in the simulations
+)
+
+// playFrom continues a game from s, with current to move, and returns the
+// winner (0 or 1).
+func playFrom(rng *rand.Rand, s score, current int, strategy0, strategy1 strategy) int {
+	strategies := []strategy{strategy0, strategy1}
+	var turnIsOver bool
+	for s.player+s.thisTurn < win {
+		action := strategies[current](s)
+		s, turnIsOver = action(s, rng)
+		if turnIsOver {
+			current = 1 - current
+		}
+	}
+	return current
+}
+
+// winOdds estimates the probability that player 0, about to move at s,
+// wins if it takes action a first.
+func winOdds(rng *rand.Rand, s score, a action, opponent strategy) float64 {
+	wins := 0
+	for n := 0; n < oddsRollouts; n++ {
+		next, turnIsOver := a(s, rng)
+		current := 0
+		if turnIsOver {
+			current = 1
+		}
+		if playFrom(rng, next, current, stayAtK(oddsHoldAt), opponent) == 0 {
+			wins++
+		}
+	}
+	return float64(wins) / oddsRollouts
+}
+
+// An interactiveGame is a game of Pig between a person and a strategy.
+type interactiveGame struct {
+	in    *bufio.Scanner
+	out   io.Writer
+	rng   *rand.Rand
+	odds  *rand.Rand // for simulations, so they don't change the dice
+	botK  int        // the computer plays stayAtK(botK)
+	moves []string
+}
+
+// record adds a move to the log and prints it.
+func (g *interactiveGame) record(format string, args ...any) {
+	move := fmt.Sprintf(format, args...)
+	g.moves = append(g.moves, move)
+	fmt.Fprintln(g.out, move)
+}
+
+// ask shows the state of the game and reads commands until one of them is
+// a move. It reports whether the person chose to roll.
+func (g *interactiveGame) ask(s score) (bool, error) {
+	fmt.Fprintf(g.out, "You: %d  Computer: %d  This turn: %d\n", s.player, s.opponent, s.thisTurn)
+	fmt.Fprintf(g.out, "Odds of winning: roll %.0f%%, stay %.0f%%\n",
+		100*winOdds(g.odds, s, roll, stayAtK(g.botK)), 100*winOdds(g.odds, s, stay, stayAtK(g.botK)))
+	for {
+		fmt.Fprint(g.out, "[r]oll, [s]tay, [l]og or [q]uit? ")
+		if !g.in.Scan() {
+			if err := g.in.Err(); err != nil {
+				return false, err
+			}
+			return false, io.ErrUnexpectedEOF
+		}
+		switch strings.ToLower(strings.TrimSpace(g.in.Text())) {
+		case "r", "roll":
+			return true, nil
+		case "s", "stay", "h", "hold":
+			return false, nil
+		case "l", "log":
+			for _, m := range g.moves {
+				fmt.Fprintln(g.out, " ", m)
+			}
+		case "q", "quit":
+			return false, errQuit
+		default:
+			fmt.Fprintln(g.out, "Please answer r, s, l or q.")
+		}
+	}
+}
+
+var errQuit = errors.New("quit")
+
+// play takes a turn for who, rolling or staying, and records the outcome.
+func (g *interactiveGame) play(who string, s score, isRoll bool) (score, bool) {
+	if !isRoll {
+		g.record("%s stayed, banking %d for a total of %d.", who, s.thisTurn, s.player+s.thisTurn)
+		return stay(s, g.rng)
+	}
+	result, turnIsOver := roll(s, g.rng)
+	if turnIsOver {
+		g.record("%s rolled 1 and lost %d points.", who, s.thisTurn)
+	} else {
+		g.record("%s rolled %d: %d this turn.", who, result.thisTurn-s.thisTurn, result.thisTurn)
+	}
+	return result, turnIsOver
+}
+
+// PigInteractive plays a game of Pig between a person, whose commands are
+// read from in, and stayAtK(k). Everything is written to out. The dice are
+// rolled with a random number generator seeded with seed. It returns an
+// error if in ends before the game does.
+func PigInteractive(in io.Reader, out io.Writer, k int, seed int64) error {
+	g := &interactiveGame{
+		in:   bufio.NewScanner(in),
+		out:  out,
+		rng:  rand.New(rand.NewSource(seed)),
+		odds: rand.New(rand.NewSource(seed + 1)),
+		botK: k,
+	}
+	fmt.Fprintf(out, "Playing Pig to %d against a computer staying at %d.\n", win, k)
+
+	var s score
+	var turnIsOver bool
+	current := g.rng.Intn(2) // 0 is the person, 1 the computer
+	for s.player+s.thisTurn < win {
+		if current == 0 {
+			isRoll, err := g.ask(s)
+			if err == errQuit {
+				fmt.Fprintln(out, "Bye.")
+				return nil
+			} else if err != nil {
+				return fmt.Errorf("reading move: %w", err)
+			}
+			s, turnIsOver = g.play("You", s, isRoll)
+		} else {
+			s, turnIsOver = g.play("Computer", s, s.thisTurn < g.botK) // as stayAtK
+		}
+		if turnIsOver {
+			current = 1 - current
+		}
+	}
+
+	if current == 0 {
+		fmt.Fprintln(out, "You win!")
+	} else {
+		fmt.Fprintln(out, "The computer wins.")
+	}
+	return nil
+}
@@ -0,0 +1,91 @@
+package idiomaticgo
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestPigInteractiveScripted(t *testing.T) {
+	var out bytes.Buffer
+	if err := PigInteractive(strings.NewReader("l\nroll\nR\nmaybe\ns\nq\n"), &out, 20, 1); err != nil {
+		t.Fatal(err)
+	}
+	want := `Playing Pig to 100 against a computer staying at 20.
+Computer rolled 4: 4 this turn.
+Computer rolled 6: 10 this turn.
+Computer rolled 6: 16 this turn.
+Computer rolled 2: 18 this turn.
+Computer rolled 1 and lost 18 points.
+You: 0  Computer: 0  This turn: 0
+Odds of winning: roll 52%, stay 46%
+[r]oll, [s]tay, [l]og or [q]uit?   Computer rolled 4: 4 this turn.
+  Computer rolled 6: 10 this turn.
+  Computer rolled 6: 16 this turn.
+  Computer rolled 2: 18 this turn.
+  Computer rolled 1 and lost 18 points.
+[r]oll, [s]tay, [l]og or [q]uit? You rolled 2: 2 this turn.
+You: 0  Computer: 0  This turn: 2
+Odds of winning: roll 54%, stay 50%
+[r]oll, [s]tay, [l]og or [q]uit? You rolled 3: 5 this turn.
+You: 0  Computer: 0  This turn: 5
+Odds of winning: roll 53%, stay 45%
+[r]oll, [s]tay, [l]og or [q]uit? Please answer r, s, l or q.
+[r]oll, [s]tay, [l]og or [q]uit? You stayed, banking 5 for a total of 5.
+Computer rolled 5: 5 this turn.
+Computer rolled 1 and lost 5 points.
+You: 5  Computer: 0  This turn: 0
+Odds of winning: roll 56%, stay 52%
+[r]oll, [s]tay, [l]og or [q]uit? Bye.
+`
+	if got := out.String(); got != want {
+		t.Errorf("transcript:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestPigInteractivePlaysToTheEnd(t *testing.T) {
+	// Roll twice, then stay, until the game is over.
+	script := strings.Repeat("r\nr\ns\n", 200)
+	var out bytes.Buffer
+	if err := PigInteractive(strings.NewReader(script), &out, 20, 7); err != nil {
+		t.Fatal(err)
+	}
+	transcript := out.String()
+	if !strings.HasSuffix(transcript, "You win!\n") && !strings.HasSuffix(transcript, "The computer wins.\n") {
+		t.Errorf("game did not finish:\n%s", transcript)
+	}
+	// Every banked total must add up to the points banked before it.
+	you, computer, stays := 0, 0, 0
+	for _, line := range strings.Split(transcript, "\n") {
+		var who string
+		var banked, total int
+		if n, _ := fmt.Sscanf(line, "%s stayed, banking %d for a total of %d.", &who, &banked, &total); n == 3 {
+			stays++
+			switch who {
+			case "You":
+				you += banked
+				if you != total {
+					t.Fatalf("%q: you have %d", line, you)
+				}
+			case "Computer":
+				computer += banked
+				if computer != total {
+					t.Fatalf("%q: the computer has %d", line, computer)
+				}
+			}
+		}
+	}
+	if stays == 0 {
+		t.Error("nobody stayed")
+	}
+}
+
+func TestPigInteractiveInputEnds(t *testing.T) {
+	err := PigInteractive(strings.NewReader("r\n"), io.Discard, 20, 1)
+	if !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Errorf("got %v; want %v", err, io.ErrUnexpectedEOF)
+	}
+}
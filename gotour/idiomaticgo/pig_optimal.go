@@ -0,0 +1,197 @@
+package idiomaticgo
+
+/*
+The optimal strategy for Pig
+============================
+
+The stayAtK strategies are simple, but they ignore most of the score: a player
+who needs 2 points to win should not stay at 25, and a player far behind
+should take more risks. The optimal strategy is the one that maximizes the
+probability of winning from every score.
+
+Let P(i, j, k) be the probability that the player to move wins, when that
+player has i points, the opponent has j points and k points have been
+accumulated in this turn. If i+k reaches win the player has already won, so
+P is 1. Otherwise the player may
+
+    roll: with probability 1/6 a 1 is rolled and the opponent moves next
+          from (j, i, 0); otherwise the turn total grows by 2 to 6
+    stay: the opponent moves next from (j, i+k, 0)
+
+so
+
+    P(i, j, k) = max(Proll, Pstay)
+    Proll      = (1 - P(j, i, 0))/6 + sum over r in 2..6 of P(i, j, k+r)/6
+    Pstay      = 1 - P(j, i+k, 0)
+
+Staying with k = 0 only passes the turn, so it is never considered.
+
+Value iteration
+===============
+
+These equations refer to each other in a cycle (P(i, j, 0) depends on
+P(j, i, 0) and the other way round), so they cannot be solved by a simple
+recursion. Instead we start with all probabilities at 0 and repeatedly
+replace each P by the right-hand side of its equation. Every sweep brings
+the values closer to the solution; we stop once no value changes by more
+than solveEpsilon.
+
+Scores never go down, so the states can be solved in layers: all states with
+the same i+j only depend on each other and on states with a larger i+j. We
+solve the layers from the largest score sum down, which needs only a few
+sweeps per layer.
+
+The same technique computes the probability that one fixed strategy beats
+another; pigEvaluate uses it to compare the optimal strategy with stayAtK.
+*/
+
+import (
+	"fmt"
+	"math"
+	"runtime"
+	"sync"
+)
+
+const solveEpsilon = 1e-9 // convergence threshold of value iteration
+
+// pigTable holds a value for every score (player, opponent, thisTurn)
+// with player+thisTurn < win.
+type pigTable [win][win][win]float64
+
+// A pigPolicy is the solution of Pig: the win probability of every score
+// under optimal play, and whether to roll there.
+type pigPolicy struct {
+	p    pigTable
+	roll [win][win][win]bool
+}
+
+// winProb returns the probability that the player to move wins from
+// (i, j, k), counting a turn total that reaches win as a win.
+func (pp *pigPolicy) winProb(i, j, k int) float64 {
+	if i+k >= win {
+		return 1
+	}
+	return pp.p[i][j][k]
+}
+
+// solvePig computes the optimal policy for Pig by value iteration.
+func solvePig() *pigPolicy {
+	pp := new(pigPolicy)
+	forEachLayer(func(i, j int) float64 {
+		delta := 0.0
+		for k := win - 1 - i; k >= 0; k-- {
+			pRoll := (1 - pp.p[j][i][0]) / 6
+			for r := 2; r <= 6; r++ {
+				pRoll += pp.winProb(i, j, k+r) / 6
+			}
+			best, roll := pRoll, true
+			if k > 0 {
+				if pStay := 1 - pp.p[j][i+k][0]; pStay > pRoll {
+					best, roll = pStay, false
+				}
+			}
+			delta = math.Max(delta, math.Abs(best-pp.p[i][j][k]))
+			pp.p[i][j][k], pp.roll[i][j][k] = best, roll
+		}
+		return delta
+	})
+	return pp
+}
+
+// forEachLayer sweeps update over every (player, opponent) pair, one layer
+// of equal player+opponent at a time from the highest down, until the
+// largest change update reports for a sweep of the layer is below
+// solveEpsilon.
+func forEachLayer(update func(i, j int) float64) {
+	for sum := 2 * (win - 1); sum >= 0; sum-- {
+		for {
+			delta := 0.0
+			for i := max(0, sum-win+1); i <= min(sum, win-1); i++ {
+				delta = math.Max(delta, update(i, sum-i))
+			}
+			if delta < solveEpsilon {
+				break
+			}
+		}
+	}
+}
+
+// strategy returns the policy as a strategy for play and roundRobin.
+func (pp *pigPolicy) strategy() strategy {
+	return func(s score) action {
+		if pp.roll[s.player][s.opponent][s.thisTurn] {
+			return roll
+		}
+		return stay
+	}
+}
+
+// pigEvaluate returns the probability that the policy beats an opponent
+// who rolls whenever opponentRolls says so, when the first player is chosen
+// at random as in play.
+func pigEvaluate(pp *pigPolicy, opponentRolls func(score) bool) float64 {
+	// us[i][j][k] is our win probability when we are to move with i points,
+	// them[i][j][k] when the opponent is to move with i points.
+	us, them := new(pigTable), new(pigTable)
+	get := func(t *pigTable, i, j, k int, reached float64) float64 {
+		if i+k >= win {
+			return reached
+		}
+		return t[i][j][k]
+	}
+	forEachLayer(func(i, j int) float64 {
+		delta := 0.0
+		for k := win - 1 - i; k >= 0; k-- {
+			var v float64
+			if pp.roll[i][j][k] {
+				v = them[j][i][0] / 6
+				for r := 2; r <= 6; r++ {
+					v += get(us, i, j, k+r, 1) / 6
+				}
+			} else {
+				v = them[j][i+k][0]
+			}
+			delta = math.Max(delta, math.Abs(v-us[i][j][k]))
+			us[i][j][k] = v
+
+			if opponentRolls(score{i, j, k}) {
+				v = us[j][i][0] / 6
+				for r := 2; r <= 6; r++ {
+					v += get(them, i, j, k+r, 0) / 6
+				}
+			} else {
+				v = us[j][i+k][0]
+			}
+			delta = math.Max(delta, math.Abs(v-them[i][j][k]))
+			them[i][j][k] = v
+		}
+		return delta
+	})
+	return (us[0][0][0] + them[0][0][0]) / 2
+}
+
+// PigOptimal solves Pig and reports the win probability of the optimal
+// strategy against every stayAtK strategy.
+func PigOptimal() {
+	pp := solvePig()
+	fmt.Printf("Optimal first player wins with probability %.4f\n", pp.p[0][0][0])
+
+	// The evaluations are independent, so run them in parallel.
+	probs := make([]float64, win)
+	var wg sync.WaitGroup
+	sem := make(chan bool, runtime.NumCPU())
+	for k := range probs {
+		wg.Add(1)
+		go func(k int) {
+			defer wg.Done()
+			sem <- true
+			probs[k] = pigEvaluate(pp, func(s score) bool { return s.thisTurn < k+1 })
+			<-sem
+		}(k)
+	}
+	wg.Wait()
+
+	for k, p := range probs {
+		fmt.Printf("Optimal vs staying at k =% 4d: wins %.4f\n", k+1, p)
+	}
+}
@@ -0,0 +1,333 @@
+package idiomaticgo
+
+/*
+Rating strategies
+=================
+
+A win/loss ratio depends on who a strategy happened to play: beating many weak
+strategies looks as good as beating a few strong ones. Rating systems fix this
+by predicting the outcome of every game from the ratings of both players and
+moving each rating by how much better or worse the player did than predicted.
+
+Elo
+---
+
+Elo gives every player a single number. The expected score of a player rated
+Ra against one rated Rb is
+
+    E = 1 / (1 + 10^((Rb-Ra)/400))
+
+and after each game the rating moves by eloK * (score - E).
+
+Glicko-2
+--------
+
+Glicko-2 also tracks how certain a rating is. The rating deviation (RD) is
+large for a new player and shrinks as games are played; a rating is
+"Rating ± 2 RD" with 95% confidence. The volatility measures how erratic a
+player's results are. Results are processed in rating periods; here every
+round of a tournament is one period. See Glickman, "Example of the Glicko-2
+system", for the formulas used by glicko.update.
+
+Tournament formats
+==================
+
+A ladder keeps the ratings of a set of strategies across rounds, so different
+formats can be run on top of it:
+
+    round robin  every strategy plays every other strategy once
+    swiss        each round pairs strategies of similar rating that have not
+                 met yet, so few rounds separate strong from weak
+    knockout     strategies are seeded by rating and the loser of every
+                 series is eliminated until one remains
+
+Every round is played on the worker pool of a tournament, with a seed derived
+from the ladder seed and the round number, so results are reproducible.
+*/
+
+import (
+	"fmt"
+	"math"
+	"sort"
+)
+
+const (
+	eloStart   = 1500.0   // initial Elo rating
+	eloK       = 4.0      // Elo adjustment per game
+	glickoTau  = 0.5      // Glicko-2 constraint on volatility change
+	glickoUnit = 173.7178 // Glicko-2 scale factor, 400/ln(10)
+)
+
+// glicko is a Glicko-2 rating on the original Glicko scale.
+type glicko struct {
+	rating, rd, vol float64
+}
+
+func newGlicko() glicko {
+	return glicko{rating: 1500, rd: 350, vol: 0.06}
+}
+
+// A glickoResult records games against one opponent in a rating period.
+type glickoResult struct {
+	opponent    glicko // the opponent's rating at the start of the period
+	wins, games int
+}
+
+// update returns the rating after a rating period with the given results.
+// Results of no games carry no information and are ignored, so a period
+// without games only widens the rating deviation.
+func (g glicko) update(results []glickoResult) glicko {
+	var played []glickoResult
+	for _, r := range results {
+		if r.games > 0 {
+			played = append(played, r)
+		}
+	}
+	results = played
+	mu, phi := (g.rating-1500)/glickoUnit, g.rd/glickoUnit
+	if len(results) == 0 {
+		phi = math.Sqrt(phi*phi + g.vol*g.vol)
+		return glicko{g.rating, math.Min(phi*glickoUnit, 350), g.vol}
+	}
+
+	// Estimated variance v and improvement delta from the game outcomes.
+	var vInv, sum float64
+	for _, r := range results {
+		muj, phij := (r.opponent.rating-1500)/glickoUnit, r.opponent.rd/glickoUnit
+		gj := 1 / math.Sqrt(1+3*phij*phij/(math.Pi*math.Pi))
+		e := 1 / (1 + math.Exp(-gj*(mu-muj)))
+		vInv += float64(r.games) * gj * gj * e * (1 - e)
+		sum += gj * (float64(r.wins) - float64(r.games)*e)
+	}
+	v := 1 / vInv
+	delta := v * sum
+
+	// New volatility, by the Illinois variant of regula falsi.
+	a := math.Log(g.vol * g.vol)
+	f := func(x float64) float64 {
+		ex := math.Exp(x)
+		d := phi*phi + v + ex
+		return ex*(delta*delta-phi*phi-v-ex)/(2*d*d) - (x-a)/(glickoTau*glickoTau)
+	}
+	A, B := a, 0.0
+	if delta*delta > phi*phi+v {
+		B = math.Log(delta*delta - phi*phi - v)
+	} else {
+		k := 1.0
+		for f(a-k*glickoTau) < 0 {
+			k++
+		}
+		B = a - k*glickoTau
+	}
+	fA, fB := f(A), f(B)
+	for math.Abs(B-A) > 1e-6 {
+		C := A + (A-B)*fA/(fB-fA)
+		fC := f(C)
+		if fC*fB <= 0 {
+			A, fA = B, fB
+		} else {
+			fA /= 2
+		}
+		B, fB = C, fC
+	}
+	vol := math.Exp(A / 2)
+
+	phiStar := math.Sqrt(phi*phi + vol*vol)
+	phi = 1 / math.Sqrt(1/(phiStar*phiStar)+1/v)
+	mu += phi * phi * sum
+	return glicko{1500 + glickoUnit*mu, glickoUnit * phi, vol}
+}
+
+// eloExpected returns the expected score of a player rated ra against
+// a player rated rb.
+func eloExpected(ra, rb float64) float64 {
+	return 1 / (1 + math.Pow(10, (rb-ra)/400))
+}
+
+// A ratedPlayer is a strategy with its ratings and record.
+type ratedPlayer struct {
+	name         string
+	elo          float64
+	glicko       glicko
+	wins, losses int
+}
+
+// A ladder rates strategies over rounds of series.
+type ladder struct {
+	players []*ratedPlayer
+	t       tournament // plays the series; its seed is the ladder seed
+	round   int
+}
+
+func newLadder(names []string, strategies []strategy, gamesPerSeries, workers int, seed int64) *ladder {
+	l := &ladder{t: tournament{strategies, gamesPerSeries, workers, seed}}
+	for _, name := range names {
+		l.players = append(l.players, &ratedPlayer{name: name, elo: eloStart, glicko: newGlicko()})
+	}
+	return l
+}
+
+// playRound plays the series in pairs as one rating period and updates the
+// ratings of every player. It returns the series in the order of pairs.
+func (l *ladder) playRound(pairs []series) []series {
+	t := l.t
+	t.seed = seriesSeed(l.t.seed, l.round, -1)
+	l.round++
+	played := t.playAll(pairs)
+	order := make(map[[2]int]int, len(pairs))
+	for k, sr := range pairs {
+		order[[2]int{sr.i, sr.j}] = k
+	}
+	sort.Slice(played, func(a, b int) bool {
+		return order[[2]int{played[a].i, played[a].j}] < order[[2]int{played[b].i, played[b].j}]
+	})
+
+	n := t.gamesPerSeries
+	eloDelta := make([]float64, len(l.players))
+	results := make([][]glickoResult, len(l.players))
+	for _, sr := range played {
+		a, b := l.players[sr.i], l.players[sr.j]
+		d := eloK * (float64(sr.wins0) - float64(n)*eloExpected(a.elo, b.elo))
+		eloDelta[sr.i] += d
+		eloDelta[sr.j] -= d
+		results[sr.i] = append(results[sr.i], glickoResult{b.glicko, sr.wins0, n})
+		results[sr.j] = append(results[sr.j], glickoResult{a.glicko, n - sr.wins0, n})
+		a.wins, a.losses = a.wins+sr.wins0, a.losses+n-sr.wins0
+		b.wins, b.losses = b.wins+n-sr.wins0, b.losses+sr.wins0
+	}
+	for k, p := range l.players {
+		p.elo += eloDelta[k]
+		p.glicko = p.glicko.update(results[k])
+	}
+	return played
+}
+
+// ranking returns the indexes of the players, best Glicko rating first.
+func (l *ladder) ranking() []int {
+	idx := make([]int, len(l.players))
+	for k := range idx {
+		idx[k] = k
+	}
+	sort.SliceStable(idx, func(a, b int) bool {
+		return l.players[idx[a]].glicko.rating > l.players[idx[b]].glicko.rating
+	})
+	return idx
+}
+
+// roundRobin plays every pair of players once, in a single round.
+func (l *ladder) roundRobin() {
+	var pairs []series
+	for i := range l.players {
+		for j := i + 1; j < len(l.players); j++ {
+			pairs = append(pairs, series{i: i, j: j})
+		}
+	}
+	l.playRound(pairs)
+}
+
+// swiss plays rounds of the Swiss system: each player meets the closest
+// ranked player it has not met yet. With an odd number of players, the
+// lowest ranked unpaired player sits the round out.
+func (l *ladder) swiss(rounds int) {
+	met := make(map[[2]int]bool)
+	for r := 0; r < rounds; r++ {
+		order := l.ranking()
+		paired := make([]bool, len(l.players))
+		var pairs []series
+		for a, p := range order {
+			if paired[p] {
+				continue
+			}
+			q := -1
+			for _, c := range order[a+1:] {
+				if paired[c] {
+					continue
+				}
+				if q < 0 {
+					q = c // rematch only if there is nobody new
+				}
+				if !met[[2]int{p, c}] {
+					q = c
+					break
+				}
+			}
+			if q < 0 {
+				break // bye
+			}
+			paired[p], paired[q] = true, true
+			met[[2]int{p, q}], met[[2]int{q, p}] = true, true
+			pairs = append(pairs, series{i: p, j: q})
+		}
+		l.playRound(pairs)
+	}
+}
+
+// knockout seeds the players by rating and plays single elimination rounds,
+// best seed against worst, until one player is left. The best seed gets a
+// bye when the number of players is odd, and wins a tied series. It returns
+// the index of the winner.
+func (l *ladder) knockout() int {
+	alive := l.ranking()
+	for len(alive) > 1 {
+		var next []int
+		if len(alive)%2 == 1 {
+			next, alive = append(next, alive[0]), alive[1:]
+		}
+		var pairs []series
+		for a, b := 0, len(alive)-1; a < b; a, b = a+1, b-1 {
+			pairs = append(pairs, series{i: alive[a], j: alive[b]})
+		}
+		for _, sr := range l.playRound(pairs) {
+			if 2*sr.wins0 >= l.t.gamesPerSeries {
+				next = append(next, sr.i)
+			} else {
+				next = append(next, sr.j)
+			}
+		}
+		// Keep the survivors in seed order for the next round.
+		seed := make(map[int]int)
+		for k, p := range l.ranking() {
+			seed[p] = k
+		}
+		sort.Slice(next, func(a, b int) bool { return seed[next[a]] < seed[next[b]] })
+		alive = next
+	}
+	return alive[0]
+}
+
+// printLeaderboard prints the players ranked by Glicko rating.
+func (l *ladder) printLeaderboard() {
+	fmt.Printf("%4s  %-10s %8s %6s %8s  %s\n", "Rank", "Strategy", "Glicko", "RD", "Elo", "Wins, losses")
+	for rank, k := range l.ranking() {
+		p := l.players[k]
+		fmt.Printf("%4d  %-10s %8.1f %6.1f %8.1f  %s\n", rank+1, p.name,
+			p.glicko.rating, p.glicko.rd, p.elo, ratioString(p.wins, p.losses))
+	}
+}
+
+// PigRatings rates the optimal strategy and the stayAtK strategies with Elo
+// and Glicko-2 over a tournament of the given format ("roundrobin", "swiss"
+// or "knockout"), and prints the leaderboard. rounds is the number of Swiss
+// rounds; the other formats ignore it.
+func PigRatings(format string, rounds, gamesPerSeries, workers int, seed int64) error {
+	names := []string{"optimal"}
+	strategies := []strategy{solvePig().strategy()}
+	for k := 1; k <= win; k++ {
+		names = append(names, fmt.Sprintf("stayAt%d", k))
+		strategies = append(strategies, stayAtK(k))
+	}
+	l := newLadder(names, strategies, gamesPerSeries, workers, seed)
+
+	switch format {
+	case "roundrobin":
+		l.roundRobin()
+	case "swiss":
+		l.swiss(rounds)
+	case "knockout":
+		fmt.Println("Knockout winner:", names[l.knockout()])
+	default:
+		return fmt.Errorf("unknown tournament format %q", format)
+	}
+	l.printLeaderboard()
+	return nil
+}
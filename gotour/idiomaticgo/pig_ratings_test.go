@@ -0,0 +1,65 @@
+package idiomaticgo
+
+import (
+	"math"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestGlickoIgnoresEmptySeries(t *testing.T) {
+	g := newGlicko()
+	got := g.update([]glickoResult{{opponent: newGlicko()}})
+	if want := g.update(nil); got != want {
+		t.Errorf("update with a series of no games = %+v; want %+v", got, want)
+	}
+	got = g.update([]glickoResult{{opponent: newGlicko()}, {opponent: newGlicko(), wins: 3, games: 4}})
+	want := g.update([]glickoResult{{opponent: newGlicko(), wins: 3, games: 4}})
+	if got != want || math.IsNaN(got.rating) {
+		t.Errorf("update with an extra series of no games = %+v; want %+v", got, want)
+	}
+}
+
+func TestPigCommandRejectsNoGames(t *testing.T) {
+	for _, sub := range []string{"tournament", "ratings", "dice"} {
+		err := PigCommand([]string{sub, "-games", "0"}, nil, nil)
+		if err == nil || !strings.Contains(err.Error(), "-games") {
+			t.Errorf("pig %s -games 0: got error %v; want one about -games", sub, err)
+		}
+	}
+}
+
+func TestLadderWorkers(t *testing.T) {
+	names, strategies := testStrategies()
+	for _, format := range []string{"roundrobin", "swiss", "knockout"} {
+		t.Run(format, func(t *testing.T) {
+			run := func(workers int) ([]ratedPlayer, int) {
+				l := newLadder(names, strategies, 20, workers, 7)
+				winner := -1
+				switch format {
+				case "roundrobin":
+					l.roundRobin()
+				case "swiss":
+					l.swiss(4)
+				case "knockout":
+					winner = l.knockout()
+				}
+				var players []ratedPlayer
+				for _, p := range l.players {
+					players = append(players, *p)
+				}
+				return players, winner
+			}
+			want, wantWinner := run(1)
+			for _, workers := range []int{3, 8} {
+				got, winner := run(workers)
+				if winner != wantWinner {
+					t.Errorf("%d workers: winner %d; want %d as with 1 worker", workers, winner, wantWinner)
+				}
+				if !slices.Equal(got, want) {
+					t.Errorf("%d workers: players %+v; want %+v as with 1 worker", workers, got, want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,156 @@
+package idiomaticgo
+
+/*
+A parallel tournament
+=====================
+
+roundRobin plays each series one after another and takes its dice rolls from
+the global random number generator, so the results change from run to run and
+only use one core.
+
+A tournament runs the same round robin with a pool of worker goroutines. A
+feeder goroutine sends the pairs of strategies to play on a jobs channel;
+every worker plays whole series and sends the number of wins back on a results
+channel.
+
+Reproducible results
+====================
+
+Each series gets its own random number generator, seeded from the tournament
+seed and the indexes of the two strategies. Which worker plays a series, and
+when, no longer matters: the same seed gives the same wins whatever the number
+of workers.
+
+Confidence intervals
+====================
+
+A win rate measured over n games is only an estimate. For each strategy we
+report the 95% Wilson score interval: the range of true win rates that are
+consistent with the games played.
+*/
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"runtime"
+)
+
+// A tournament is a round robin between strategies.
+type tournament struct {
+	strategies     []strategy
+	gamesPerSeries int
+	workers        int   // goroutines playing series; runtime.NumCPU() if 0
+	seed           int64 // seed for every series' random number generator
+}
+
+// A series is a match between two strategies and its outcome.
+type series struct {
+	i, j  int // indexes of the strategies
+	wins0 int // games won by strategy i
+}
+
+// seriesSeed derives the seed of the series between strategies i and j
+// from the tournament seed, mixing the bits with the splitmix64 finalizer
+// so that neighbouring series get unrelated seeds.
+func seriesSeed(seed int64, i, j int) int64 {
+	z := uint64(seed) + uint64(i)<<32 + uint64(j) + 0x9e3779b97f4a7c15
+	z = (z ^ (z >> 30)) * 0xbf58476d1ce4e5b9
+	z = (z ^ (z >> 27)) * 0x94d049bb133111eb
+	return int64(z ^ (z >> 31))
+}
+
+// play runs a series to completion, with rng reseeded for the series.
+func (t *tournament) play(sr series, rng *rand.Rand) series {
+	rng.Seed(seriesSeed(t.seed, sr.i, sr.j))
+	for k := 0; k < t.gamesPerSeries; k++ {
+		if playWith(rng, t.strategies[sr.i], t.strategies[sr.j]) == 0 {
+			sr.wins0++
+		}
+	}
+	return sr
+}
+
+// playAll plays the given series on the worker pool and returns their
+// outcomes, in the order they finished.
+func (t *tournament) playAll(pairs []series) []series {
+	workers := t.workers
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+	jobs, results := make(chan series), make(chan series)
+	for w := 0; w < workers; w++ {
+		go func() {
+			rng := rand.New(rand.NewSource(0))
+			for sr := range jobs {
+				results <- t.play(sr, rng)
+			}
+		}()
+	}
+	go func() {
+		for _, sr := range pairs {
+			jobs <- sr
+		}
+		close(jobs)
+	}()
+
+	played := make([]series, len(pairs))
+	for k := range played {
+		played[k] = <-results
+	}
+	return played
+}
+
+// run plays every series and returns the wins of each strategy and the
+// number of games each strategy played.
+func (t *tournament) run() ([]int, int) {
+	n := len(t.strategies)
+	var pairs []series
+	for i := 0; i < n; i++ {
+		for j := i + 1; j < n; j++ {
+			pairs = append(pairs, series{i: i, j: j})
+		}
+	}
+
+	wins := make([]int, n)
+	for _, sr := range t.playAll(pairs) {
+		wins[sr.i] += sr.wins0
+		wins[sr.j] += t.gamesPerSeries - sr.wins0
+	}
+	return wins, t.gamesPerSeries * (n - 1)
+}
+
+// wilson returns the 95% Wilson score interval of a win rate of wins
+// out of n games.
+func wilson(wins, n int) (lo, hi float64) {
+	if n == 0 {
+		return 0, 1
+	}
+	const z = 1.96
+	p, nf := float64(wins)/float64(n), float64(n)
+	center := (p + z*z/(2*nf)) / (1 + z*z/nf)
+	margin := z / (1 + z*z/nf) * math.Sqrt(p*(1-p)/nf+z*z/(4*nf*nf))
+	return max(0, center-margin), min(1, center+margin)
+}
+
+// PigTournament plays gamesPerSeries games between every pair of stayAtK
+// strategies on workers goroutines, and prints each strategy's win rate with
+// its 95% confidence interval. The same seed always gives the same results.
+func PigTournament(gamesPerSeries, workers int, seed int64) {
+	t := &tournament{
+		strategies:     make([]strategy, win),
+		gamesPerSeries: gamesPerSeries,
+		workers:        workers,
+		seed:           seed,
+	}
+	for k := range t.strategies {
+		t.strategies[k] = stayAtK(k + 1)
+	}
+	wins, games := t.run()
+
+	for k := range t.strategies {
+		lo, hi := wilson(wins[k], games)
+		fmt.Printf("Wins, losses staying at k =% 4d: %s, win rate in [%.3f, %.3f]\n",
+			k+1, ratioString(wins[k], games-wins[k]), lo, hi)
+	}
+}
@@ -0,0 +1,37 @@
+package idiomaticgo
+
+import (
+	"fmt"
+	"slices"
+	"testing"
+)
+
+// testStrategies returns a few stayAtK strategies and their names.
+func testStrategies() ([]string, []strategy) {
+	var names []string
+	var strategies []strategy
+	for _, k := range []int{5, 10, 15, 20, 25, 30, 40} {
+		names = append(names, fmt.Sprintf("stayAt%d", k))
+		strategies = append(strategies, stayAtK(k))
+	}
+	return names, strategies
+}
+
+func TestTournamentWorkers(t *testing.T) {
+	_, strategies := testStrategies()
+	run := func(workers int) []int {
+		tr := &tournament{strategies: strategies, gamesPerSeries: 50, workers: workers, seed: 42}
+		wins, _ := tr.run()
+		return wins
+	}
+	want := run(1)
+	for _, workers := range []int{2, 4, 16} {
+		if got := run(workers); !slices.Equal(got, want) {
+			t.Errorf("%d workers: wins %v; want %v as with 1 worker", workers, got, want)
+		}
+	}
+	tr := &tournament{strategies: strategies, gamesPerSeries: 50, workers: 1, seed: 43}
+	if wins, _ := tr.run(); slices.Equal(wins, want) {
+		t.Errorf("seeds 42 and 43 both give wins %v", wins)
+	}
+}
@@ -5,8 +5,11 @@
 package idiomaticgo
 
 import (
+	"context"
 	"log"
 	"net/http"
+	"os"
+	"sort"
 	"time"
 )
 
@@ -145,7 +148,9 @@ The Sleep method
 
 Sleep calls time.Sleep to pause before sending the Resource to done. The pause
 will either be of a fixed length (pollInterval) plus an additional delay
-proportional to the number of sequential errors (r.errCount).
+computed by the Resource's Backoff policy from the number of sequential
+errors (r.errCount). The default policy adds errTimeout per error, up to
+maxBackoff.
 
 This is an example of a typical Go idiom: a function intended to run inside
 a goroutine takes a channel, upon which it sends its return value
@@ -175,12 +180,30 @@ Notice that this goroutine owns the urlStatus data structure, ensuring that
 it can only be accessed sequentially. This prevents memory corruption issues
 that might arise from parallel reads and/or writes to a shared map. 
 
+Reading the state
+=================
+
+Other goroutines that want to look at urlStatus, such as the HTTP handlers
+of the status server, must not read the map directly. Instead they send a
+reply channel on reads, and the monitor goroutine answers with a copy of the
+current state. The map still has a single owner.
+
+The monitor goroutine also watches the updates for URLs going down or coming
+back up and hands alerts to the Notifiers given to NewMonitor; see alerts.go.
+It keeps a history of recent polls of each URL as well; see history.go.
+
 */
 const (
-	numPollers     = 2                // number of Poller goroutines to launch
-	pollInterval   = 60 * time.Second // how often to poll each URL
-	statusInterval = 10 * time.Second // how often to log status to stdout
-	errTimeout     = 10 * time.Second // back-off timeout on error
+	numPollers     = 2                  // number of Poller goroutines to launch
+	pollInterval   = 60 * time.Second   // how often to poll each URL
+	statusInterval = 10 * time.Second   // how often to log status to stdout
+	errTimeout     = 10 * time.Second   // back-off timeout on error
+	maxBackoff     = 10 * time.Minute   // longest back-off timeout
+	breakAfter     = 5                  // consecutive errors that open the circuit
+	breakCooldown  = 5 * time.Minute    // how long an open circuit skips polls
+	statusAddr     = "localhost:8080"   // address of the status HTTP server
+	historyFile    = "url_history.json" // where poll history is persisted
+	saveInterval   = 5 * time.Minute    // how often to persist poll history
 )
 
 var urls = []string{
@@ -191,68 +214,218 @@ var urls = []string{
 
 // State represents the last-known state of a URL.
 type State struct {
-	url    string
-	status string
+	url      string
+	status   string
+	checked  time.Time     // when the poll started
+	latency  time.Duration // how long the poll took
+	code     int           // HTTP status code, 0 if the poll failed
+	errClass string        // class of the poll error, "" if none
+	errCount int           // consecutive errors, including this poll
+	breaker  BreakerState  // state of the Resource's circuit breaker
 }
 
-// StateMonitor maintains a map that stores the state of the URLs being
-// polled, and prints the current state every updateInterval nanoseconds.
-// It returns a chan State to which resource state should be sent.
-func StateMonitor(updateInterval time.Duration) chan<- State {
-	updates := make(chan State)
-	urlStatus := make(map[string]string)
+// A Monitor owns the state of the URLs being polled. The state is only ever
+// touched by the monitor goroutine; other goroutines send it updates and
+// ask it for snapshots over channels.
+type Monitor struct {
+	updates chan State
+	reads   chan chan []State
+	history chan func(map[string]*ring)
+	cancel  context.CancelFunc
+	done    chan struct{} // closed when the monitor goroutine returns
+}
+
+// NewMonitor launches the monitor goroutine, which maintains a map that
+// stores the state of the URLs being polled, and prints the current state
+// every updateInterval nanoseconds. Alerts about URLs going down, coming
+// back up or flapping are sent to notifiers. The goroutine runs until Stop
+// is called.
+func NewMonitor(updateInterval time.Duration, notifiers ...Notifier) *Monitor {
+	ctx, cancel := context.WithCancel(context.Background())
+	m := &Monitor{
+		updates: make(chan State),
+		reads:   make(chan chan []State),
+		history: make(chan func(map[string]*ring)),
+		cancel:  cancel,
+		done:    make(chan struct{}),
+	}
+	urlStatus := make(map[string]State)
+	history := make(map[string]*ring)
 	ticker := time.NewTicker(updateInterval)
+	alerts := make(chan Alert, alertQueue)
+	go dispatch(alerts, notifiers)
+	transitions := newAlerter()
 	go func() {
+		defer close(m.done)
+		defer close(alerts)
+		defer ticker.Stop()
 		for {
 			select {
+			case <-ctx.Done():
+				return
 			case <-ticker.C:
 				logState(urlStatus)
-			case s := <-updates:
-				urlStatus[s.url] = s.status
+			case s := <-m.updates:
+				if prev, ok := urlStatus[s.url]; ok && prev.breaker != s.breaker {
+					log.Printf("Circuit %s %s -> %s", s.url, prev.breaker, s.breaker)
+				}
+				urlStatus[s.url] = s
+				if s.breaker != BreakerOpen {
+					r := history[s.url]
+					if r == nil {
+						r = new(ring)
+						history[s.url] = r
+					}
+					r.add(sample{s.checked, s.latency, s.code, s.errClass})
+				}
+				if a, ok := transitions.observe(s); ok && len(notifiers) > 0 {
+					select {
+					case alerts <- a:
+					default:
+						log.Println("Alert queue full, dropping", a)
+					}
+				}
+			case reply := <-m.reads:
+				reply <- snapshot(urlStatus)
+			case f := <-m.history:
+				f(history)
 			}
 		}
 	}()
-	return updates
+	return m
+}
+
+// Stop stops the monitor goroutine and the delivery of its alerts, and
+// waits for the goroutine to return. The Monitor must not be used after
+// Stop; alerts still queued are delivered in the background.
+func (m *Monitor) Stop() {
+	m.cancel()
+	<-m.done
+}
+
+// Updates returns the channel to which resource state should be sent.
+func (m *Monitor) Updates() chan<- State {
+	return m.updates
+}
+
+// Snapshot asks the monitor goroutine for a copy of the current state,
+// sorted by URL.
+func (m *Monitor) Snapshot() []State {
+	reply := make(chan []State)
+	m.reads <- reply
+	return <-reply
+}
+
+// StateMonitor maintains a map that stores the state of the URLs being
+// polled, and prints the current state every updateInterval nanoseconds.
+// It returns a chan State to which resource state should be sent.
+func StateMonitor(updateInterval time.Duration) chan<- State {
+	return NewMonitor(updateInterval).Updates()
+}
+
+// snapshot copies a state map into a slice sorted by URL.
+func snapshot(s map[string]State) []State {
+	states := make([]State, 0, len(s))
+	for _, st := range s {
+		states = append(states, st)
+	}
+	sort.Slice(states, func(i, j int) bool { return states[i].url < states[j].url })
+	return states
 }
 
 // logState prints a state map.
-func logState(s map[string]string) {
+func logState(s map[string]State) {
 	log.Println("Current state:")
 	for k, v := range s {
-		log.Printf(" %s %s", k, v)
+		log.Printf(" %s %s", k, v.status)
 	}
 }
 
+// up reports whether the poll that produced s counts as up; see pollUp.
+func (s State) up() bool {
+	return pollUp(s.code)
+}
+
 // Resource represents an HTTP URL to be polled by this program.
 type Resource struct {
 	url      string
 	errCount int
+	code     int    // status code of the last poll
+	errClass string // class of the last poll error
+	backoff  Backoff
+	delay    time.Duration // last back-off delay
+	breaker  *Breaker
+}
+
+// NewResource returns a Resource for url that backs off on errors according
+// to policy and is guarded by breaker. A nil policy adds no delay on errors
+// and a nil breaker never opens.
+func NewResource(url string, policy Backoff, breaker *Breaker) *Resource {
+	return &Resource{url: url, backoff: policy, breaker: breaker}
 }
 
 // Poll executes an HTTP HEAD request for url
 // and returns the HTTP status string or an error string.
+// A response that does not count as up (see pollUp), such as a server
+// error, counts as an error for the back-off and the circuit breaker.
 func (r *Resource) Poll() string {
 	resp, err := http.Head(r.url)
 	if err != nil {
 		log.Println("Error", r.url, err)
+		r.code, r.errClass = 0, errorClass(err)
 		r.errCount++
+		r.breaker.Failure(time.Now())
 		return err.Error()
 	}
+	resp.Body.Close()
+	r.code, r.errClass = resp.StatusCode, ""
+	if !pollUp(r.code) {
+		r.errCount++
+		r.breaker.Failure(time.Now())
+		return resp.Status
+	}
 	r.errCount = 0
+	r.breaker.Success()
 	return resp.Status
 }
 
 // Sleep sleeps for an appropriate interval (dependent on error state)
 // before sending the Resource to done.
 func (r *Resource) Sleep(done chan<- *Resource) {
-	time.Sleep(pollInterval + errTimeout*time.Duration(r.errCount))
+	var delay time.Duration
+	if r.backoff != nil {
+		delay = r.backoff.Delay(r.errCount, r.delay)
+	}
+	r.delay = delay
+	time.Sleep(pollInterval + delay)
 	done <- r
 }
 
 func Poller(in <-chan *Resource, out chan<- *Resource, status chan<- State) {
 	for r := range in {
+		start := time.Now()
+		if !r.breaker.Allow(start) {
+			status <- State{
+				url:      r.url,
+				status:   "circuit open",
+				checked:  start,
+				errCount: r.errCount,
+				breaker:  r.breaker.State(),
+			}
+			out <- r
+			continue
+		}
 		s := r.Poll()
-		status <- State{r.url, s}
+		status <- State{
+			url:      r.url,
+			status:   s,
+			checked:  start,
+			latency:  time.Since(start),
+			code:     r.code,
+			errClass: r.errClass,
+			errCount: r.errCount,
+			breaker:  r.breaker.State(),
+		}
 		out <- r
 	}
 }
@@ -261,8 +434,27 @@ func ShareMemory() {
 	// Create our input and output channels.
 	pending, complete := make(chan *Resource), make(chan *Resource)
 
-	// Launch the StateMonitor.
-	status := StateMonitor(statusInterval)
+	// Launch the StateMonitor and serve its state over HTTP.
+	monitor := NewMonitor(statusInterval, NotifierFunc(func(a Alert) error {
+		log.Println("Alert:", a)
+		return nil
+	}))
+	status := monitor.Updates()
+	go func() {
+		log.Println(http.ListenAndServe(statusAddr, monitor.Handler()))
+	}()
+
+	// Pick up the poll history of the previous run, and save it regularly.
+	if err := monitor.LoadHistory(historyFile); err != nil && !os.IsNotExist(err) {
+		log.Println("Error loading history", err)
+	}
+	go func() {
+		for range time.Tick(saveInterval) {
+			if err := monitor.SaveHistory(historyFile); err != nil {
+				log.Println("Error saving history", err)
+			}
+		}
+	}()
 
 	// Launch some Poller goroutines.
 	for i := 0; i < numPollers; i++ {
@@ -272,7 +464,9 @@ func ShareMemory() {
 	// Send some Resources to the pending queue.
 	go func() {
 		for _, url := range urls {
-			pending <- &Resource{url: url}
+			policy := LinearBackoff{Step: errTimeout, Max: maxBackoff}
+			breaker := &Breaker{Threshold: breakAfter, Cooldown: breakCooldown}
+			pending <- NewResource(url, policy, breaker)
 		}
 	}()
 
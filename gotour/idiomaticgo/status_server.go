@@ -0,0 +1,137 @@
+package idiomaticgo
+
+/*
+Serving the state over HTTP
+===========================
+
+The Monitor's Handler exposes the state of the polled URLs to the outside
+world:
+
+    /status       the current state as JSON
+    /status.html  the current state as an HTML table
+    /metrics      the current state in the Prometheus text format
+    /stats        uptime and latency statistics as JSON
+
+Every handler takes a Snapshot (or the Stats) of the monitor, so requests are
+served from a copy made by the monitor goroutine itself and urlStatus is never
+shared.
+*/
+
+import (
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// stateView is the exported form of a State used by the JSON and HTML views.
+type stateView struct {
+	URL               string    `json:"url"`
+	Status            string    `json:"status"`
+	LastChecked       time.Time `json:"last_checked"`
+	LatencyMillis     float64   `json:"latency_ms"`
+	ConsecutiveErrors int       `json:"consecutive_errors"`
+	Circuit           string    `json:"circuit"`
+}
+
+func newStateView(s State) stateView {
+	return stateView{
+		URL:               s.url,
+		Status:            s.status,
+		LastChecked:       s.checked,
+		LatencyMillis:     float64(s.latency) / float64(time.Millisecond),
+		ConsecutiveErrors: s.errCount,
+		Circuit:           s.breaker.String(),
+	}
+}
+
+// Handler returns an http.Handler serving the monitor's state.
+func (m *Monitor) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/status", m.serveJSON)
+	mux.HandleFunc("/status.html", m.serveHTML)
+	mux.HandleFunc("/metrics", m.serveMetrics)
+	mux.HandleFunc("/stats", m.serveStats)
+	return mux
+}
+
+func (m *Monitor) views() []stateView {
+	states := m.Snapshot()
+	views := make([]stateView, len(states))
+	for i, s := range states {
+		views[i] = newStateView(s)
+	}
+	return views
+}
+
+func (m *Monitor) serveJSON(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(m.views()); err != nil {
+		log.Println("Error", r.URL, err)
+	}
+}
+
+func (m *Monitor) serveStats(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(m.Stats()); err != nil {
+		log.Println("Error", r.URL, err)
+	}
+}
+
+var statusTemplate = template.Must(template.New("status").Parse(`<!DOCTYPE html>
+<html>
+<head><title>URL status</title></head>
+<body>
+<table>
+<tr><th>URL</th><th>Status</th><th>Last checked</th><th>Latency (ms)</th><th>Consecutive errors</th><th>Circuit</th></tr>
+{{range .}}<tr><td>{{.URL}}</td><td>{{.Status}}</td><td>{{.LastChecked.Format "2006-01-02 15:04:05"}}</td><td>{{printf "%.1f" .LatencyMillis}}</td><td>{{.ConsecutiveErrors}}</td><td>{{.Circuit}}</td></tr>
+{{end}}</table>
+</body>
+</html>
+`))
+
+func (m *Monitor) serveHTML(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if err := statusTemplate.Execute(w, m.views()); err != nil {
+		log.Println("Error", r.URL, err)
+	}
+}
+
+func (m *Monitor) serveMetrics(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	states := m.Snapshot()
+	metric := func(name, typ, help string, value func(State) float64) {
+		fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, typ)
+		for _, s := range states {
+			fmt.Fprintf(w, "%s{url=\"%s\"} %g\n", name, promLabel(s.url), value(s))
+		}
+	}
+	metric("url_up", "gauge", "Whether the last poll of the URL got a response other than a server error.",
+		func(s State) float64 {
+			if s.up() {
+				return 1
+			}
+			return 0
+		})
+	metric("url_poll_latency_seconds", "gauge", "Duration of the last poll.",
+		func(s State) float64 { return s.latency.Seconds() })
+	metric("url_consecutive_errors", "gauge", "Number of consecutive failed polls.",
+		func(s State) float64 { return float64(s.errCount) })
+	metric("url_circuit_open", "gauge", "Whether the circuit breaker of the URL is open.",
+		func(s State) float64 {
+			if s.breaker == BreakerOpen {
+				return 1
+			}
+			return 0
+		})
+	metric("url_last_checked_timestamp_seconds", "gauge", "Unix time of the last poll.",
+		func(s State) float64 { return float64(s.checked.UnixNano()) / 1e9 })
+}
+
+// promLabel escapes a label value for the Prometheus text format.
+func promLabel(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`).Replace(s)
+}
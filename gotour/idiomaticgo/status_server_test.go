@@ -0,0 +1,153 @@
+package idiomaticgo
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// polledMonitor returns a Monitor that has seen one poll of a server
+// answering 200 and one of a server answering 503, and their URLs.
+func polledMonitor(t *testing.T) (m *Monitor, okURL, downURL string) {
+	ok := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	t.Cleanup(ok.Close)
+	down := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	t.Cleanup(down.Close)
+
+	m = NewMonitor(time.Hour)
+	t.Cleanup(m.Stop)
+	in, out := make(chan *Resource), make(chan *Resource, 2)
+	go Poller(in, out, m.Updates())
+	in <- NewResource(ok.URL, nil, nil)
+	in <- NewResource(down.URL, nil, nil)
+	close(in)
+	<-out
+	<-out
+	return m, ok.URL, down.URL
+}
+
+// get serves a GET request for path with h and checks its content type.
+func get(t *testing.T, h http.Handler, path, contentType string) string {
+	t.Helper()
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("GET", path, nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("GET %s: status %d", path, w.Code)
+	}
+	if got := w.Header().Get("Content-Type"); !strings.HasPrefix(got, contentType) {
+		t.Errorf("GET %s: Content-Type %q; want %q", path, got, contentType)
+	}
+	return w.Body.String()
+}
+
+func TestStatusJSON(t *testing.T) {
+	m, okURL, downURL := polledMonitor(t)
+	var views []stateView
+	if err := json.Unmarshal([]byte(get(t, m.Handler(), "/status", "application/json")), &views); err != nil {
+		t.Fatal(err)
+	}
+	if len(views) != 2 {
+		t.Fatalf("got %d states; want 2", len(views))
+	}
+	for _, v := range views {
+		switch v.URL {
+		case okURL:
+			if v.Status != "200 OK" || v.ConsecutiveErrors != 0 {
+				t.Errorf("%s: status %q with %d errors; want 200 OK with 0", v.URL, v.Status, v.ConsecutiveErrors)
+			}
+		case downURL:
+			if v.Status != "503 Service Unavailable" || v.ConsecutiveErrors != 1 {
+				t.Errorf("%s: status %q with %d errors; want 503 Service Unavailable with 1", v.URL, v.Status, v.ConsecutiveErrors)
+			}
+		default:
+			t.Errorf("unexpected URL %q", v.URL)
+		}
+		if v.Circuit != "closed" || v.LastChecked.IsZero() {
+			t.Errorf("%s: circuit %q, last checked %v", v.URL, v.Circuit, v.LastChecked)
+		}
+	}
+}
+
+func TestStatusHTML(t *testing.T) {
+	m, okURL, downURL := polledMonitor(t)
+	body := get(t, m.Handler(), "/status.html", "text/html")
+	for _, want := range []string{
+		"<td>" + okURL + "</td><td>200 OK</td>",
+		"<td>" + downURL + "</td><td>503 Service Unavailable</td>",
+		"<td>1</td><td>closed</td>",
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("/status.html does not contain %q:\n%s", want, body)
+		}
+	}
+}
+
+func TestMetrics(t *testing.T) {
+	m, okURL, downURL := polledMonitor(t)
+	body := get(t, m.Handler(), "/metrics", "text/plain")
+
+	// Parse the samples into metrics[name][url].
+	metrics := make(map[string]map[string]float64)
+	s := bufio.NewScanner(strings.NewReader(body))
+	for s.Scan() {
+		line := s.Text()
+		if strings.HasPrefix(line, "#") {
+			continue
+		}
+		name, rest, ok := strings.Cut(line, `{url="`)
+		url, value, ok2 := strings.Cut(rest, `"} `)
+		v, err := strconv.ParseFloat(value, 64)
+		if !ok || !ok2 || err != nil {
+			t.Fatalf("bad sample %q", line)
+		}
+		if metrics[name] == nil {
+			metrics[name] = make(map[string]float64)
+		}
+		metrics[name][url] = v
+	}
+
+	for _, url := range []string{okURL, downURL} {
+		up, errs := metrics["url_up"][url], metrics["url_consecutive_errors"][url]
+		if (up == 1) != (errs == 0) {
+			t.Errorf("%s: url_up %v but url_consecutive_errors %v", url, up, errs)
+		}
+		if metrics["url_circuit_open"][url] != 0 {
+			t.Errorf("%s: circuit open", url)
+		}
+		if ts := metrics["url_last_checked_timestamp_seconds"][url]; ts < float64(time.Now().Add(-time.Minute).Unix()) {
+			t.Errorf("%s: last checked at %v", url, ts)
+		}
+	}
+	if metrics["url_up"][okURL] != 1 || metrics["url_up"][downURL] != 0 {
+		t.Errorf("url_up = %v; want 1 for %s and 0 for %s", metrics["url_up"], okURL, downURL)
+	}
+	for _, name := range []string{"url_up", "url_poll_latency_seconds", "url_consecutive_errors"} {
+		if !strings.Contains(body, "# TYPE "+name+" gauge\n") {
+			t.Errorf("no TYPE line for %s", name)
+		}
+	}
+}
+
+func TestStatsJSON(t *testing.T) {
+	m, okURL, downURL := polledMonitor(t)
+	var stats []Stats
+	if err := json.Unmarshal([]byte(get(t, m.Handler(), "/stats", "application/json")), &stats); err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]float64{okURL: 100, downURL: 0}
+	if len(stats) != len(want) {
+		t.Fatalf("got stats of %d URLs; want %d", len(stats), len(want))
+	}
+	for _, st := range stats {
+		if up, ok := want[st.URL]; !ok || st.Samples != 1 || st.Uptime1h != up || st.Uptime24h != up {
+			t.Errorf("%s: %d samples, uptime %v%% and %v%%; want 1 sample, %v%%", st.URL, st.Samples, st.Uptime1h, st.Uptime24h, up)
+		}
+	}
+}
@@ -0,0 +1,246 @@
+package ioexamples
+
+/*
+Counting like wc
+================
+
+WordCount counts the words of a string with a bufio.Scanner. Count does the
+job of the Unix wc command for any io.Reader: it reads the input in blocks and
+counts, in one pass,
+
+    lines            newline characters
+    words            runs of characters that are not white space
+    bytes            bytes
+    runes            UTF-8 encoded characters; an invalid byte counts as one
+    max line length  the widest line, in characters, with tabs expanded to
+                     the next multiple of 8
+
+White space is what unicode.IsSpace says it is, so words are also separated by
+non-breaking and other Unicode spaces. A character can be split between two
+blocks; the bytes of the incomplete character are carried over to the next
+block.
+
+WCCommand counts files on several goroutines at once: a file's counts do not
+depend on any other file's. The rows are printed in the order of the
+arguments, whichever file finishes first.
+*/
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"runtime"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Counts are the statistics of a text.
+type Counts struct {
+	Name          string `json:"name,omitempty"`
+	Lines         int64  `json:"lines"`
+	Words         int64  `json:"words"`
+	Bytes         int64  `json:"bytes"`
+	Runes         int64  `json:"runes"`
+	MaxLineLength int64  `json:"maxLineLength"`
+}
+
+// add adds the counts of d to c, keeping the longer of the maximum lengths.
+func (c *Counts) add(d Counts) {
+	c.Lines += d.Lines
+	c.Words += d.Words
+	c.Bytes += d.Bytes
+	c.Runes += d.Runes
+	c.MaxLineLength = max(c.MaxLineLength, d.MaxLineLength)
+}
+
+// Count returns the Counts of the text read from r.
+func Count(r io.Reader) (Counts, error) {
+	var c Counts
+	var lineLength int64
+	inWord := false
+	buf := make([]byte, 64<<10)
+	carried := 0 // bytes of an incomplete rune at the start of buf
+	for {
+		n, err := r.Read(buf[carried:])
+		c.Bytes += int64(n)
+		data := buf[:carried+n]
+		atEOF := err != nil
+		i := 0
+		for i < len(data) {
+			r, size := rune(data[i]), 1
+			if r >= utf8.RuneSelf {
+				if !atEOF && !utf8.FullRune(data[i:]) {
+					break // finish the rune with the next block
+				}
+				r, size = utf8.DecodeRune(data[i:])
+			}
+			i += size
+			c.Runes++
+			switch r {
+			case '\n':
+				c.Lines++
+				c.MaxLineLength = max(c.MaxLineLength, lineLength)
+				lineLength = 0
+			case '\t':
+				lineLength += 8 - lineLength%8
+			default:
+				if unicode.IsGraphic(r) {
+					lineLength++
+				}
+			}
+			if space := unicode.IsSpace(r); !space && !inWord {
+				c.Words++
+				inWord = true
+			} else if space {
+				inWord = false
+			}
+		}
+		carried = copy(buf, data[i:])
+		if err == io.EOF {
+			c.MaxLineLength = max(c.MaxLineLength, lineLength)
+			return c, nil
+		} else if err != nil {
+			return c, err
+		}
+	}
+}
+
+// CountFile returns the Counts of the file name.
+func CountFile(name string) (Counts, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return Counts{Name: name}, err
+	}
+	defer f.Close()
+	c, err := Count(f)
+	c.Name = name
+	return c, err
+}
+
+// countResult is the outcome of counting the file at index in the list.
+type countResult struct {
+	index  int
+	counts Counts
+	err    error
+}
+
+// countFiles counts the files on workers goroutines and sends the results on
+// the returned channel in the order of names.
+func countFiles(names []string, workers int) <-chan countResult {
+	jobs, results := make(chan int), make(chan countResult)
+	for w := 0; w < workers; w++ {
+		go func() {
+			for i := range jobs {
+				c, err := CountFile(names[i])
+				results <- countResult{i, c, err}
+			}
+		}()
+	}
+	go func() {
+		for i := range names {
+			jobs <- i
+		}
+		close(jobs)
+	}()
+
+	ordered := make(chan countResult)
+	go func() {
+		pending := make(map[int]countResult)
+		for next := 0; next < len(names); {
+			r := <-results
+			pending[r.index] = r
+			for r, ok := pending[next]; ok; r, ok = pending[next] {
+				delete(pending, next)
+				ordered <- r
+				next++
+			}
+		}
+		close(ordered)
+	}()
+	return ordered
+}
+
+// WCCommand runs the wc command with the given arguments:
+//
+//	wc [-l] [-w] [-c] [-m] [-L] [-json] [-workers n] [file ...]
+//
+// counts the lines, words, bytes, runes and maximum line length of the files,
+// or of stdin if there are none, and prints a row for each file and a total
+// row if there are several. The flags choose the columns; by default they
+// are lines, words and bytes. With -json it prints all the counts as JSON.
+// Files that cannot be read are reported on stderr, and the other files are
+// still counted.
+func WCCommand(args []string, stdin io.Reader, stdout, stderr io.Writer) error {
+	fs := flag.NewFlagSet("wc", flag.ContinueOnError)
+	lines := fs.Bool("l", false, "print the line counts")
+	words := fs.Bool("w", false, "print the word counts")
+	bytes := fs.Bool("c", false, "print the byte counts")
+	runes := fs.Bool("m", false, "print the character counts")
+	maxLine := fs.Bool("L", false, "print the maximum line lengths")
+	asJSON := fs.Bool("json", false, "print all counts as JSON")
+	workers := fs.Int("workers", runtime.NumCPU(), "files to count at once")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if !*lines && !*words && !*bytes && !*runes && !*maxLine {
+		*lines, *words, *bytes = true, true, true
+	}
+
+	var rows []Counts
+	failed := 0
+	if fs.NArg() == 0 {
+		c, err := Count(stdin)
+		if err != nil {
+			return err
+		}
+		rows = append(rows, c)
+	}
+	for r := range countFiles(fs.Args(), max(*workers, 1)) {
+		if r.err != nil {
+			fmt.Fprintf(stderr, "wc: %v\n", r.err)
+			failed++
+			continue
+		}
+		rows = append(rows, r.counts)
+	}
+	total := Counts{Name: "total"}
+	for _, c := range rows {
+		total.add(c)
+	}
+
+	if *asJSON {
+		enc := json.NewEncoder(stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(struct {
+			Files []Counts `json:"files"`
+			Total Counts   `json:"total"`
+		}{rows, total}); err != nil {
+			return err
+		}
+	} else {
+		if len(rows) > 1 {
+			rows = append(rows, total)
+		}
+		for _, c := range rows {
+			for _, col := range []struct {
+				show bool
+				n    int64
+			}{{*lines, c.Lines}, {*words, c.Words}, {*bytes, c.Bytes}, {*runes, c.Runes}, {*maxLine, c.MaxLineLength}} {
+				if col.show {
+					fmt.Fprintf(stdout, " %7d", col.n)
+				}
+			}
+			if c.Name != "" {
+				fmt.Fprintf(stdout, " %s", c.Name)
+			}
+			fmt.Fprintln(stdout)
+		}
+	}
+	if failed > 0 {
+		return errors.New("wc: some files could not be read")
+	}
+	return nil
+}
@@ -0,0 +1,142 @@
+package ioexamples
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+func TestCount(t *testing.T) {
+	for _, tt := range []struct {
+		text string
+		want Counts
+	}{
+		{"", Counts{}},
+		{"hello world\n", Counts{Lines: 1, Words: 2, Bytes: 12, Runes: 12, MaxLineLength: 11}},
+		{"héllo wörld\n日本語 テキスト\n\tx\n", Counts{Lines: 3, Words: 5, Bytes: 40, Runes: 24, MaxLineLength: 11}},
+		{"no newline", Counts{Lines: 0, Words: 2, Bytes: 10, Runes: 10, MaxLineLength: 10}},
+		{"a\u00a0b c", Counts{Words: 3, Bytes: 6, Runes: 5, MaxLineLength: 5}}, // a non-breaking space
+		// Invalid bytes, and an incomplete rune at the end, count as one
+		// character each.
+		{"\xff\xfe ok\n", Counts{Lines: 1, Words: 2, Bytes: 6, Runes: 6, MaxLineLength: 5}},
+		{"日本\xe8", Counts{Words: 1, Bytes: 7, Runes: 3, MaxLineLength: 3}},
+	} {
+		for _, r := range []struct {
+			name string
+			r    io.Reader
+		}{
+			{"whole", strings.NewReader(tt.text)},
+			// Reading a byte at a time splits every multi-byte rune
+			// between reads.
+			{"one byte", iotest.OneByteReader(strings.NewReader(tt.text))},
+			{"data and EOF", iotest.DataErrReader(iotest.OneByteReader(strings.NewReader(tt.text)))},
+		} {
+			got, err := Count(r.r)
+			if err != nil {
+				t.Fatalf("Count(%q) read %s: %v", tt.text, r.name, err)
+			}
+			if got != tt.want {
+				t.Errorf("Count(%q) read %s = %+v; want %+v", tt.text, r.name, got, tt.want)
+			}
+		}
+	}
+}
+
+func TestCountError(t *testing.T) {
+	r := io.MultiReader(strings.NewReader("one two\n"), iotest.ErrReader(iotest.ErrTimeout))
+	if _, err := Count(r); err != iotest.ErrTimeout {
+		t.Errorf("Count: error %v; want %v", err, iotest.ErrTimeout)
+	}
+}
+
+// wcFiles writes n files with i+1 lines of two words each to a temporary
+// directory and returns their names.
+func wcFiles(t *testing.T, n int) []string {
+	dir := t.TempDir()
+	var names []string
+	for i := range n {
+		name := filepath.Join(dir, fmt.Sprintf("f%02d.txt", i))
+		if err := os.WriteFile(name, []byte(strings.Repeat("ab cd\n", i+1)), 0644); err != nil {
+			t.Fatal(err)
+		}
+		names = append(names, name)
+	}
+	return names
+}
+
+func TestWCCommandOrder(t *testing.T) {
+	names := wcFiles(t, 20)
+	var out, errOut strings.Builder
+	args := append([]string{"-workers", "8", "-l", "-w", "-c"}, names...)
+	if err := WCCommand(args, nil, &out, &errOut); err != nil {
+		t.Fatal(err, errOut.String())
+	}
+	var want strings.Builder
+	for i, name := range names {
+		fmt.Fprintf(&want, " %7d %7d %7d %s\n", i+1, 2*(i+1), 6*(i+1), name)
+	}
+	fmt.Fprintf(&want, " %7d %7d %7d total\n", 210, 420, 1260)
+	if out.String() != want.String() {
+		t.Errorf("output:\n%s\nwant:\n%s", out.String(), want.String())
+	}
+}
+
+func TestWCCommandMissingFile(t *testing.T) {
+	names := wcFiles(t, 2)
+	missing := filepath.Join(t.TempDir(), "missing.txt")
+	var out, errOut strings.Builder
+	err := WCCommand([]string{"-L", names[0], missing, names[1]}, nil, &out, &errOut)
+	if err == nil {
+		t.Error("no error for a missing file")
+	}
+	if !strings.Contains(errOut.String(), "missing.txt") {
+		t.Errorf("stderr %q does not name the missing file", errOut.String())
+	}
+	want := fmt.Sprintf(" %7d %s\n %7d %s\n %7d total\n", 5, names[0], 5, names[1], 5)
+	if out.String() != want {
+		t.Errorf("output:\n%s\nwant:\n%s", out.String(), want)
+	}
+}
+
+func TestWCCommandStdin(t *testing.T) {
+	var out strings.Builder
+	if err := WCCommand([]string{"-m"}, strings.NewReader("日本語\n"), &out, io.Discard); err != nil {
+		t.Fatal(err)
+	}
+	if want := fmt.Sprintf(" %7d\n", 4); out.String() != want {
+		t.Errorf("output %q; want %q", out.String(), want)
+	}
+}
+
+func TestWCCommandJSON(t *testing.T) {
+	names := wcFiles(t, 3)
+	var out strings.Builder
+	if err := WCCommand(append([]string{"-json", "-l"}, names...), nil, &out, io.Discard); err != nil {
+		t.Fatal(err)
+	}
+	var got struct {
+		Files []Counts `json:"files"`
+		Total Counts   `json:"total"`
+	}
+	if err := json.Unmarshal([]byte(out.String()), &got); err != nil {
+		t.Fatalf("%v in\n%s", err, out.String())
+	}
+	if len(got.Files) != len(names) {
+		t.Fatalf("%d files in the JSON; want %d", len(got.Files), len(names))
+	}
+	for i, c := range got.Files {
+		want := Counts{Name: names[i], Lines: int64(i + 1), Words: int64(2 * (i + 1)), Bytes: int64(6 * (i + 1)), Runes: int64(6 * (i + 1)), MaxLineLength: 5}
+		if c != want {
+			t.Errorf("file %d: %+v; want %+v", i, c, want)
+		}
+	}
+	want := Counts{Name: "total", Lines: 6, Words: 12, Bytes: 36, Runes: 36, MaxLineLength: 5}
+	if got.Total != want {
+		t.Errorf("total: %+v; want %+v", got.Total, want)
+	}
+}
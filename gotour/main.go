@@ -1,9 +1,55 @@
 package main
 
 import (
+	"fmt"
+	"os"
+
 	"gotour/concurrency"
+	"gotour/idiomaticgo"
+	"gotour/ioexamples"
+	"gotour/maps"
+	"gotour/sorting"
+	"gotour/sortingseraching"
 )
 
+// commands are the programs that can be run by name, as in "gotour markov
+// train -o model.bin corpus.txt".
+var commands = map[string]func(args []string) error{
+	"markov": func(args []string) error {
+		return idiomaticgo.MarkovCommand(args, os.Stdin, os.Stdout)
+	},
+	"pig": func(args []string) error {
+		return idiomaticgo.PigCommand(args, os.Stdin, os.Stdout)
+	},
+	"extsort": func(args []string) error {
+		return sorting.ExtSortCommand(args, os.Stdin, os.Stdout)
+	},
+	"guess": func(args []string) error {
+		return sortingseraching.GuessCommand(args, os.Stdin, os.Stdout)
+	},
+	"sortviz": func(args []string) error {
+		return sorting.SortVizCommand(args, os.Stdout)
+	},
+	"wc": func(args []string) error {
+		return ioexamples.WCCommand(args, os.Stdin, os.Stdout, os.Stderr)
+	},
+	"wordfreq": func(args []string) error {
+		return maps.WordFreqCommand(args, os.Stdin, os.Stdout)
+	},
+	"search": func(args []string) error {
+		return maps.SearchCommand(args, os.Stdout)
+	},
+}
+
 func main() {
+	if len(os.Args) > 1 {
+		if cmd, ok := commands[os.Args[1]]; ok {
+			if err := cmd(os.Args[2:]); err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				os.Exit(1)
+			}
+			return
+		}
+	}
 	concurrency.BasicSync()
 }
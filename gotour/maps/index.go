@@ -0,0 +1,334 @@
+package maps
+
+/*
+Searching documents
+===================
+
+WordCount gives the words of one string. An Index keeps the word counts of
+many documents, turned around: for every term, the documents it occurs in
+and how often. This is an inverted index, and it answers "which documents
+contain these words?" without reading the documents again.
+
+Terms are the words of ScanUnicodeWords, case folded and, if the index is
+built with Stem, stemmed. Queries are split the same way, so "Connecting"
+finds a document about connections.
+
+Not every matching document is equally relevant. Search ranks them in one
+of two ways, where f is the number of times a term occurs in a document, df
+the number of documents it occurs in and N the number of documents:
+
+    TFIDF  the cosine of the angle between the query and the document, as
+           vectors of term weights (1 + ln f)·ln(N/df). A term in every
+           document weighs nothing; a rare term weighs a lot.
+    BM25   the sum, over the terms of the query, of
+
+               ln(1 + (N - df + 0.5)/(df + 0.5)) · f·(k1 + 1) / (f + k1·(1 - b + b·len/avglen))
+
+           with k1 = 1.2 and b = 0.75. Each occurrence of a term adds less
+           than the one before, and matches in long documents count for
+           less than in short ones. This is what most search engines use.
+
+An Index is saved with Save and loaded with LoadIndex, so a corpus is read
+once and searched many times. Any number of goroutines may search an index at
+once, but adding documents must not happen at the same time as anything else:
+the TF-IDF vector lengths of the documents are computed once, by the first
+search after the last Add, under a sync.Once.
+*/
+
+import (
+	"bufio"
+	"bytes"
+	"cmp"
+	"fmt"
+	"io"
+	"io/fs"
+	"math"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// BM25 parameters: k1 sets how quickly repeated terms stop adding to the
+// score, and b how much the document length counts.
+const (
+	bm25K1 = 1.2
+	bm25B  = 0.75
+)
+
+// A Ranking is a way of scoring documents against a query.
+type Ranking int
+
+const (
+	BM25 Ranking = iota
+	TFIDF
+)
+
+// String returns the name of the ranking, "bm25" or "tfidf".
+func (r Ranking) String() string {
+	switch r {
+	case BM25:
+		return "bm25"
+	case TFIDF:
+		return "tfidf"
+	}
+	return fmt.Sprintf("Ranking(%d)", int(r))
+}
+
+// posting is an occurrence of a term: the document and how often the term
+// occurs in it.
+type posting struct {
+	doc, freq int
+}
+
+// A Document is an indexed document: its name and the number of terms in it.
+type Document struct {
+	Name   string
+	Length int
+}
+
+// IndexOptions configure how an Index splits documents and queries into
+// terms.
+type IndexOptions struct {
+	Stem bool // index the Porter stems of the words
+	Stop bool // leave out EnglishStopWords
+}
+
+// An Index is an inverted index of documents.
+type Index struct {
+	opts     IndexOptions
+	docs     []Document
+	names    map[string]int
+	postings map[string][]posting // in document order
+	length   int                  // the total length of the documents
+
+	normsOnce sync.Once // guards norms
+	norms     []float64 // TF-IDF vector lengths of the documents
+}
+
+// NewIndex returns an empty Index.
+func NewIndex(opts IndexOptions) *Index {
+	return &Index{opts: opts, names: make(map[string]int), postings: make(map[string][]posting)}
+}
+
+// Options returns the options the index was built with.
+func (idx *Index) Options() IndexOptions {
+	return idx.opts
+}
+
+// Documents returns the indexed documents, in the order they were added.
+func (idx *Index) Documents() []Document {
+	return slices.Clone(idx.docs)
+}
+
+// Terms returns the number of distinct terms in the index.
+func (idx *Index) Terms() int {
+	return len(idx.postings)
+}
+
+// DocFreq returns the number of documents term occurs in. The term is
+// normalized as the words of documents are.
+func (idx *Index) DocFreq(term string) int {
+	terms := idx.terms(term)
+	if len(terms) != 1 {
+		return 0
+	}
+	for t := range terms {
+		return len(idx.postings[t])
+	}
+	return 0
+}
+
+// terms returns the terms of text and the number of times each occurs.
+func (idx *Index) terms(text string) map[string]int {
+	f, _ := idx.count(strings.NewReader(text))
+	return f.counts
+}
+
+// count counts the terms read from r.
+func (idx *Index) count(r io.Reader) (*Frequencies, error) {
+	opts := WordOptions{Fold: true, Stem: idx.opts.Stem}
+	if idx.opts.Stop {
+		opts.StopWords = EnglishStopWords
+	}
+	f := NewFrequencies(opts)
+	return f, f.Add(r)
+}
+
+// Add indexes the text read from r as the document name.
+func (idx *Index) Add(name string, r io.Reader) error {
+	if _, ok := idx.names[name]; ok {
+		return fmt.Errorf("maps: document %q is already indexed", name)
+	}
+	f, err := idx.count(r)
+	if err != nil {
+		return fmt.Errorf("maps: indexing %s: %w", name, err)
+	}
+	doc := len(idx.docs)
+	idx.docs = append(idx.docs, Document{name, f.Total()})
+	idx.names[name] = doc
+	idx.length += f.Total()
+	for term, n := range f.counts {
+		idx.postings[term] = append(idx.postings[term], posting{doc, n})
+	}
+	idx.normsOnce, idx.norms = sync.Once{}, nil
+	return nil
+}
+
+// AddFile indexes the named file, under its name.
+func (idx *Index) AddFile(name string) error {
+	f, err := os.Open(name)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return idx.Add(name, f)
+}
+
+// AddDir indexes the text files in the directory dir and its
+// subdirectories, under their paths. Files and directories whose names
+// start with a '.', and files that are not text, are skipped.
+func (idx *Index) AddDir(dir string) error {
+	return filepath.WalkDir(dir, func(name string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if name != dir && strings.HasPrefix(d.Name(), ".") {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		f, err := os.Open(name)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		br := bufio.NewReader(f)
+		if head, _ := br.Peek(512); bytes.IndexByte(head, 0) >= 0 {
+			return nil // binary
+		}
+		return idx.Add(name, br)
+	})
+}
+
+// A Hit is a document matching a query, and its score.
+type Hit struct {
+	Name  string
+	Score float64
+}
+
+// Search returns the n best matches for query, best first, scored with
+// ranking. A document matches if it contains any term of the query. If n is
+// 0 or less, Search returns all the matches.
+func (idx *Index) Search(query string, ranking Ranking, n int) []Hit {
+	var scores map[int]float64
+	switch ranking {
+	case BM25:
+		scores = idx.bm25(idx.terms(query))
+	case TFIDF:
+		scores = idx.tfidf(idx.terms(query))
+	default:
+		panic("maps: unknown ranking " + ranking.String())
+	}
+	docs := make([]int, 0, len(scores))
+	for doc := range scores {
+		docs = append(docs, doc)
+	}
+	slices.SortFunc(docs, func(a, b int) int {
+		if c := cmp.Compare(scores[b], scores[a]); c != 0 {
+			return c
+		}
+		return cmp.Compare(a, b)
+	})
+	if n > 0 && n < len(docs) {
+		docs = docs[:n]
+	}
+	hits := make([]Hit, len(docs))
+	for i, doc := range docs {
+		hits[i] = Hit{idx.docs[doc].Name, scores[doc]}
+	}
+	return hits
+}
+
+// idf returns ln(N/df), the inverse document frequency of a term that
+// occurs in df documents.
+func (idx *Index) idf(df int) float64 {
+	return math.Log(float64(len(idx.docs)) / float64(df))
+}
+
+// tfidfWeight returns the weight of a term that occurs freq times in a
+// document and in df documents.
+func (idx *Index) tfidfWeight(freq, df int) float64 {
+	return (1 + math.Log(float64(freq))) * idx.idf(df)
+}
+
+// computeNorms computes the TF-IDF vector lengths of the documents.
+func (idx *Index) computeNorms() {
+	idx.norms = make([]float64, len(idx.docs))
+	for _, postings := range idx.postings {
+		for _, p := range postings {
+			w := idx.tfidfWeight(p.freq, len(postings))
+			idx.norms[p.doc] += w * w
+		}
+	}
+	for i, sum := range idx.norms {
+		idx.norms[i] = math.Sqrt(sum)
+	}
+}
+
+// tfidf returns the cosine similarities between the query with terms and
+// the documents containing them. Documents whose only matching terms occur in
+// every document, and so weigh nothing, are left out.
+func (idx *Index) tfidf(terms map[string]int) map[int]float64 {
+	idx.normsOnce.Do(idx.computeNorms)
+
+	scores := make(map[int]float64)
+	var queryNorm float64
+	for term, qf := range terms {
+		postings := idx.postings[term]
+		if len(postings) == 0 {
+			continue
+		}
+		qw := idx.tfidfWeight(qf, len(postings))
+		queryNorm += qw * qw
+		for _, p := range postings {
+			scores[p.doc] += qw * idx.tfidfWeight(p.freq, len(postings))
+		}
+	}
+	queryNorm = math.Sqrt(queryNorm)
+	for doc, dot := range scores {
+		if dot == 0 {
+			delete(scores, doc)
+			continue
+		}
+		scores[doc] = dot / (queryNorm * idx.norms[doc])
+	}
+	return scores
+}
+
+// bm25 returns the BM25 scores of the documents containing terms. A term
+// repeated in the query counts as often as it is repeated.
+func (idx *Index) bm25(terms map[string]int) map[int]float64 {
+	scores := make(map[int]float64)
+	if len(idx.docs) == 0 {
+		return scores
+	}
+	n := float64(len(idx.docs))
+	avglen := float64(idx.length) / n
+	for term, qf := range terms {
+		postings := idx.postings[term]
+		df := float64(len(postings))
+		idf := math.Log(1 + (n-df+0.5)/(df+0.5))
+		for _, p := range postings {
+			f := float64(p.freq)
+			norm := 1 - bm25B + bm25B*float64(idx.docs[p.doc].Length)/max(avglen, 1)
+			scores[p.doc] += float64(qf) * idf * f * (bm25K1 + 1) / (f + bm25K1*norm)
+		}
+	}
+	return scores
+}
@@ -0,0 +1,240 @@
+package maps
+
+/*
+Index files
+===========
+
+An Index is saved in a compact binary format. All integers are unsigned
+varints (see encoding/binary):
+
+	magic      "IDX\x00"
+	version    format version
+	flags      1 if the terms are stemmed, plus 2 if stop words are left out
+	ndocs      number of documents, followed for each document by its name
+	           as a length and its bytes, and its length in terms
+	nterms     number of terms, followed for each term, in sorted order, by
+	           the term as a length and its bytes, the number of documents
+	           it occurs in and, for each of them, the difference between
+	           its number and the previous one's, and the term's frequency
+
+Document numbers only grow along a term's postings, so their differences
+are small and take one byte each in most cases.
+*/
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"slices"
+)
+
+const (
+	indexMagic   = "IDX\x00"
+	indexVersion = 1
+)
+
+// Flags recording the IndexOptions in an index file.
+const (
+	indexStem = 1 << iota
+	indexStop
+)
+
+// WriteTo writes idx to w in the index file format.
+func (idx *Index) WriteTo(w io.Writer) (int64, error) {
+	iw := &indexWriter{w: bufio.NewWriter(w)}
+	iw.write(indexMagic)
+	iw.uvarint(indexVersion)
+	var flags uint64
+	if idx.opts.Stem {
+		flags |= indexStem
+	}
+	if idx.opts.Stop {
+		flags |= indexStop
+	}
+	iw.uvarint(flags)
+	iw.uvarint(uint64(len(idx.docs)))
+	for _, d := range idx.docs {
+		iw.string(d.Name)
+		iw.uvarint(uint64(d.Length))
+	}
+	terms := make([]string, 0, len(idx.postings))
+	for term := range idx.postings {
+		terms = append(terms, term)
+	}
+	slices.Sort(terms)
+	iw.uvarint(uint64(len(terms)))
+	for _, term := range terms {
+		postings := idx.postings[term]
+		iw.string(term)
+		iw.uvarint(uint64(len(postings)))
+		prev := 0
+		for _, p := range postings {
+			iw.uvarint(uint64(p.doc - prev))
+			iw.uvarint(uint64(p.freq))
+			prev = p.doc
+		}
+	}
+	if iw.err == nil {
+		iw.err = iw.w.Flush()
+	}
+	return iw.n, iw.err
+}
+
+// indexWriter counts the bytes written to a bufio.Writer and remembers the
+// first error, so that WriteTo needs to check for errors only once.
+type indexWriter struct {
+	w   *bufio.Writer
+	n   int64
+	err error
+}
+
+func (iw *indexWriter) write(s string) {
+	if iw.err != nil {
+		return
+	}
+	n, err := iw.w.WriteString(s)
+	iw.n += int64(n)
+	iw.err = err
+}
+
+func (iw *indexWriter) uvarint(x uint64) {
+	var buf [binary.MaxVarintLen64]byte
+	iw.write(string(buf[:binary.PutUvarint(buf[:], x)]))
+}
+
+func (iw *indexWriter) string(s string) {
+	iw.uvarint(uint64(len(s)))
+	iw.write(s)
+}
+
+// ReadIndex reads an Index in the index file format from r, and checks that
+// it is consistent: terms are sorted and unique, postings are in document
+// order, every frequency is positive and the frequencies in a document add
+// up to its length.
+func ReadIndex(r io.Reader) (*Index, error) {
+	br := bufio.NewReader(r)
+	magic := make([]byte, len(indexMagic))
+	if _, err := io.ReadFull(br, magic); err != nil || string(magic) != indexMagic {
+		return nil, errors.New("maps: not an index file")
+	}
+	var err error
+	next := func() uint64 {
+		if err != nil {
+			return 0
+		}
+		var x uint64
+		x, err = binary.ReadUvarint(br)
+		return x
+	}
+	str := func() string {
+		n := next()
+		if err == nil && n > 1<<20 {
+			err = fmt.Errorf("string length %d too large", n)
+		}
+		if err != nil {
+			return ""
+		}
+		buf := make([]byte, n)
+		_, err = io.ReadFull(br, buf)
+		return string(buf)
+	}
+
+	version := next()
+	if err == nil && version != indexVersion {
+		return nil, fmt.Errorf("maps: unsupported index format version %d", version)
+	}
+	flags := next()
+	idx := NewIndex(IndexOptions{Stem: flags&indexStem != 0, Stop: flags&indexStop != 0})
+	ndocs := next()
+	for i := uint64(0); i < ndocs && err == nil; i++ {
+		name, length := str(), next()
+		if err == nil && length > math.MaxInt32 {
+			err = fmt.Errorf("document %q has length %d", name, length)
+		}
+		if _, ok := idx.names[name]; err == nil && ok {
+			err = fmt.Errorf("document %q appears twice", name)
+		}
+		idx.names[name] = len(idx.docs)
+		idx.docs = append(idx.docs, Document{name, int(length)})
+		idx.length += int(length)
+	}
+
+	lengths := make([]int, len(idx.docs))
+	nterms := next()
+	prev := ""
+	for i := uint64(0); i < nterms && err == nil; i++ {
+		term := str()
+		n := next()
+		switch {
+		case err != nil:
+		case i > 0 && term <= prev:
+			err = fmt.Errorf("term %q follows %q", term, prev)
+		case n == 0 || n > uint64(len(idx.docs)):
+			err = fmt.Errorf("term %q occurs in %d documents", term, n)
+		}
+		prev = term
+		var postings []posting
+		doc := uint64(0)
+		for k := uint64(0); k < n && err == nil; k++ {
+			delta, freq := next(), next()
+			doc += delta
+			switch {
+			case err != nil:
+			case k > 0 && delta == 0, doc >= uint64(len(idx.docs)):
+				err = fmt.Errorf("term %q has postings out of order", term)
+			case freq == 0 || freq > math.MaxInt32:
+				err = fmt.Errorf("term %q has frequency %d", term, freq)
+			default:
+				postings = append(postings, posting{int(doc), int(freq)})
+				lengths[doc] += int(freq)
+			}
+		}
+		idx.postings[term] = postings
+	}
+	if err == nil {
+		for i, d := range idx.docs {
+			if lengths[i] != d.Length {
+				err = fmt.Errorf("document %q has length %d but %d terms", d.Name, d.Length, lengths[i])
+				break
+			}
+		}
+	}
+	if err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return nil, fmt.Errorf("maps: %w", err)
+	}
+	return idx, nil
+}
+
+// Save writes idx to the file name.
+func (idx *Index) Save(name string) error {
+	f, err := os.Create(name)
+	if err != nil {
+		return err
+	}
+	_, err = idx.WriteTo(f)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// LoadIndex reads an Index saved by Save from the file name.
+func LoadIndex(name string) (*Index, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	idx, err := ReadIndex(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+	return idx, nil
+}
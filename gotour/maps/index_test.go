@@ -0,0 +1,146 @@
+package maps
+
+import (
+	"bufio"
+	"bytes"
+	"math"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// testIndex returns an index of a small corpus.
+func testIndex(t *testing.T, opts IndexOptions) *Index {
+	t.Helper()
+	idx := NewIndex(opts)
+	for _, doc := range []struct{ name, text string }{
+		{"gophers", "The gopher digs. Gophers dig tunnels, and a gopher eats roots."},
+		{"moles", "The mole digs tunnels too. A mole is not a gopher."},
+		{"cats", "The cat sleeps in the sun."},
+		{"dogs", "The dog digs in the garden and chases the cat."},
+	} {
+		if err := idx.Add(doc.name, strings.NewReader(doc.text)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return idx
+}
+
+func hitNames(hits []Hit) []string {
+	var names []string
+	for _, h := range hits {
+		names = append(names, h.Name)
+	}
+	return names
+}
+
+func TestSearchRanking(t *testing.T) {
+	idx := testIndex(t, IndexOptions{Stem: true})
+	tests := []struct {
+		query   string
+		ranking Ranking
+		want    []string
+	}{
+		{"gopher", BM25, []string{"gophers", "moles"}},
+		{"gopher", TFIDF, []string{"gophers", "moles"}},
+		{"cat sleeping", BM25, []string{"cats", "dogs"}},
+		{"cat sleeping", TFIDF, []string{"cats", "dogs"}},
+		{"digging tunnels", BM25, []string{"gophers", "moles", "dogs"}},
+		{"the", BM25, []string{"dogs", "cats", "gophers", "moles"}}, // by count, then length, then order
+		{"the", TFIDF, nil}, // in every document: it weighs nothing
+		{"zebra", BM25, nil},
+	}
+	for _, tt := range tests {
+		hits := idx.Search(tt.query, tt.ranking, 0)
+		if got := hitNames(hits); !slices.Equal(got, tt.want) {
+			t.Errorf("Search(%q, %v) = %v, want %v", tt.query, tt.ranking, got, tt.want)
+		}
+		for _, h := range hits {
+			if h.Score <= 0 {
+				t.Errorf("Search(%q, %v): %s has score %g", tt.query, tt.ranking, h.Name, h.Score)
+			}
+		}
+	}
+	if got := idx.Search("gopher", BM25, 1); len(got) != 1 || got[0].Name != "gophers" {
+		t.Errorf("Search(gopher, bm25, 1) = %v", got)
+	}
+}
+
+func TestIndexRoundTrip(t *testing.T) {
+	idx := testIndex(t, IndexOptions{Stem: true, Stop: true})
+	var buf bytes.Buffer
+	if _, err := idx.WriteTo(&buf); err != nil {
+		t.Fatal(err)
+	}
+	data := buf.Bytes()
+	loaded, err := ReadIndex(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if loaded.Options() != idx.Options() || !slices.Equal(loaded.Documents(), idx.Documents()) || loaded.Terms() != idx.Terms() {
+		t.Fatalf("loaded %v %v with %d terms, want %v %v with %d terms",
+			loaded.Options(), loaded.Documents(), loaded.Terms(), idx.Options(), idx.Documents(), idx.Terms())
+	}
+	for _, query := range []string{"gopher", "digging tunnels", "cat dog"} {
+		for _, r := range []Ranking{BM25, TFIDF} {
+			got, want := loaded.Search(query, r, 0), idx.Search(query, r, 0)
+			// The scores are sums in map order, so they may differ in the last bits.
+			if !slices.EqualFunc(got, want, func(a, b Hit) bool {
+				return a.Name == b.Name && math.Abs(a.Score-b.Score) < 1e-12
+			}) {
+				t.Errorf("Search(%q, %v) on the loaded index = %v, want %v", query, r, got, want)
+			}
+		}
+	}
+
+	if _, err := ReadIndex(bytes.NewReader(data[:len(data)-3])); err == nil {
+		t.Error("ReadIndex accepted a truncated index")
+	}
+}
+
+func TestSearchConcurrently(t *testing.T) {
+	idx := testIndex(t, IndexOptions{})
+	var wg sync.WaitGroup
+	for range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			idx.Search("gopher digs", TFIDF, 0)
+		}()
+	}
+	wg.Wait()
+}
+
+// indexFile returns an index file of one document with one occurrence of
+// each of the terms, in the given order.
+func indexFile(terms ...string) []byte {
+	var buf bytes.Buffer
+	iw := &indexWriter{w: bufio.NewWriter(&buf)}
+	iw.write(indexMagic)
+	iw.uvarint(indexVersion)
+	iw.uvarint(0)
+	iw.uvarint(1)
+	iw.string("doc")
+	iw.uvarint(uint64(len(terms)))
+	iw.uvarint(uint64(len(terms)))
+	for _, term := range terms {
+		iw.string(term)
+		iw.uvarint(1)
+		iw.uvarint(0)
+		iw.uvarint(1)
+	}
+	iw.w.Flush()
+	return buf.Bytes()
+}
+
+func TestReadIndexTermOrder(t *testing.T) {
+	if _, err := ReadIndex(bytes.NewReader(indexFile("a", "b", "c"))); err != nil {
+		t.Fatalf("sorted terms: %v", err)
+	}
+	for _, terms := range [][]string{{"b", "a"}, {"a", "a"}, {"a", "c", "b"}} {
+		if _, err := ReadIndex(bytes.NewReader(indexFile(terms...))); err == nil {
+			t.Errorf("ReadIndex accepted the terms %q", terms)
+		}
+	}
+}
@@ -0,0 +1,279 @@
+package maps
+
+/*
+Stemming
+========
+
+"connect", "connected", "connecting" and "connection" are counted as four
+words, though they are all about connecting. A stemmer removes the suffixes
+of inflected and derived words to leave a common stem, "connect". Stem
+implements the Porter stemming algorithm for English (M.F. Porter, "An
+algorithm for suffix stripping", 1980).
+
+The algorithm looks at a word as consonants (c) and vowels (v), where "y" is
+a consonant at the start of a word or after a vowel. Any word is then
+[C](VC){m}[V], and m, the measure, roughly counts the syllables. Five steps
+of rules remove or replace suffixes, most of them only if what is left has a
+large enough measure: "-ational" becomes "-ate" in "relational" (m > 0) but
+not in "rational", whose stem "r" has m = 0.
+
+Stems need not be words: "happy" becomes "happi" and "generalization"
+becomes "gener". They only have to be the same for words about the same
+thing.
+*/
+
+// A stemmer holds a word being stemmed. b[:k+1] is the current word, and j
+// marks the end of the stem when a suffix has been matched.
+type stemmer struct {
+	b    []byte
+	k, j int
+}
+
+// Stem returns the Porter stem of word, which must be lower case. Words of
+// two letters or less and words with letters other than a to z are returned
+// unchanged.
+func Stem(word string) string {
+	if len(word) <= 2 {
+		return word
+	}
+	for i := 0; i < len(word); i++ {
+		if word[i] < 'a' || word[i] > 'z' {
+			return word
+		}
+	}
+	z := &stemmer{b: []byte(word), k: len(word) - 1}
+	z.step1ab()
+	if z.k > 0 {
+		z.step1c()
+		z.step2()
+		z.step3()
+		z.step4()
+		z.step5()
+	}
+	return string(z.b[:z.k+1])
+}
+
+// cons reports whether b[i] is a consonant.
+func (z *stemmer) cons(i int) bool {
+	switch z.b[i] {
+	case 'a', 'e', 'i', 'o', 'u':
+		return false
+	case 'y':
+		return i == 0 || !z.cons(i-1)
+	}
+	return true
+}
+
+// m returns the measure of b[:j+1], the number of VC sequences in it.
+func (z *stemmer) m() int {
+	n, i := 0, 0
+	for ; i <= z.j && z.cons(i); i++ {
+	}
+	for i <= z.j {
+		for ; i <= z.j && !z.cons(i); i++ {
+		}
+		if i > z.j {
+			return n
+		}
+		n++
+		for ; i <= z.j && z.cons(i); i++ {
+		}
+	}
+	return n
+}
+
+// vowelInStem reports whether b[:j+1] contains a vowel.
+func (z *stemmer) vowelInStem() bool {
+	for i := 0; i <= z.j; i++ {
+		if !z.cons(i) {
+			return true
+		}
+	}
+	return false
+}
+
+// doublec reports whether b[i-1:i+1] is a double consonant.
+func (z *stemmer) doublec(i int) bool {
+	return i >= 1 && z.b[i] == z.b[i-1] && z.cons(i)
+}
+
+// cvc reports whether b[i-2:i+1] is consonant, vowel, consonant, and the
+// last consonant is not w, x or y. This is used to restore an e at the end
+// of a short word, as in hop(e)ing and cav(e)ing.
+func (z *stemmer) cvc(i int) bool {
+	if i < 2 || !z.cons(i) || z.cons(i-1) || !z.cons(i-2) {
+		return false
+	}
+	switch z.b[i] {
+	case 'w', 'x', 'y':
+		return false
+	}
+	return true
+}
+
+// ends reports whether b[:k+1] ends with s, and sets j to the end of the
+// rest.
+func (z *stemmer) ends(s string) bool {
+	n := len(s)
+	if n > z.k+1 || string(z.b[z.k+1-n:z.k+1]) != s {
+		return false
+	}
+	z.j = z.k - n
+	return true
+}
+
+// setTo replaces b[j+1:k+1] with s.
+func (z *stemmer) setTo(s string) {
+	z.b = append(z.b[:z.j+1], s...)
+	z.k = z.j + len(s)
+}
+
+// r replaces the suffix with s if the stem has a measure above 0.
+func (z *stemmer) r(s string) {
+	if z.m() > 0 {
+		z.setTo(s)
+	}
+}
+
+// step1ab removes plurals and -ed or -ing, as in caresses -> caress,
+// ponies -> poni, meetings -> meet and hopping -> hop.
+func (z *stemmer) step1ab() {
+	if z.b[z.k] == 's' {
+		switch {
+		case z.ends("sses"):
+			z.k -= 2
+		case z.ends("ies"):
+			z.setTo("i")
+		case z.b[z.k-1] != 's':
+			z.k--
+		}
+	}
+	if z.ends("eed") {
+		if z.m() > 0 {
+			z.k--
+		}
+	} else if (z.ends("ed") || z.ends("ing")) && z.vowelInStem() {
+		z.k = z.j
+		switch {
+		case z.ends("at"):
+			z.setTo("ate")
+		case z.ends("bl"):
+			z.setTo("ble")
+		case z.ends("iz"):
+			z.setTo("ize")
+		case z.doublec(z.k):
+			z.k--
+			switch z.b[z.k] {
+			case 'l', 's', 'z':
+				z.k++
+			}
+		default:
+			z.j = z.k
+			if z.m() == 1 && z.cvc(z.k) {
+				z.setTo("e")
+			}
+		}
+	}
+}
+
+// step1c turns a final y into i when there is another vowel in the stem.
+func (z *stemmer) step1c() {
+	if z.ends("y") && z.vowelInStem() {
+		z.b[z.k] = 'i'
+	}
+}
+
+// suffixRules are the replacements of a step, by the letter they are
+// selected by.
+type suffixRules map[byte][][2]string
+
+// step2Rules map double suffixes to single ones, as in -ization -> -ize.
+var step2Rules = suffixRules{
+	'a': {{"ational", "ate"}, {"tional", "tion"}},
+	'c': {{"enci", "ence"}, {"anci", "ance"}},
+	'e': {{"izer", "ize"}},
+	'l': {{"bli", "ble"}, {"alli", "al"}, {"entli", "ent"}, {"eli", "e"}, {"ousli", "ous"}},
+	'o': {{"ization", "ize"}, {"ation", "ate"}, {"ator", "ate"}},
+	's': {{"alism", "al"}, {"iveness", "ive"}, {"fulness", "ful"}, {"ousness", "ous"}},
+	't': {{"aliti", "al"}, {"iviti", "ive"}, {"biliti", "ble"}},
+	'g': {{"logi", "log"}},
+}
+
+// step3Rules deal with -ic-, -full, -ness and the like.
+var step3Rules = suffixRules{
+	'e': {{"icate", "ic"}, {"ative", ""}, {"alize", "al"}},
+	'i': {{"iciti", "ic"}},
+	'l': {{"ical", "ic"}, {"ful", ""}},
+	's': {{"ness", ""}},
+}
+
+// apply applies the first rule for the letter at b[at] whose suffix matches.
+func (z *stemmer) apply(rules suffixRules, at int) {
+	for _, rule := range rules[z.b[at]] {
+		if z.ends(rule[0]) {
+			z.r(rule[1])
+			return
+		}
+	}
+}
+
+func (z *stemmer) step2() {
+	if z.k > 0 {
+		z.apply(step2Rules, z.k-1)
+	}
+}
+
+func (z *stemmer) step3() {
+	z.apply(step3Rules, z.k)
+}
+
+// step4Suffixes are removed by step4 when the measure of the stem is above
+// 1, by the next to last letter of the word.
+var step4Suffixes = map[byte][]string{
+	'a': {"al"},
+	'c': {"ance", "ence"},
+	'e': {"er"},
+	'i': {"ic"},
+	'l': {"able", "ible"},
+	'n': {"ant", "ement", "ment", "ent"},
+	'o': {"ion", "ou"},
+	's': {"ism"},
+	't': {"ate", "iti"},
+	'u': {"ous"},
+	'v': {"ive"},
+	'z': {"ize"},
+}
+
+// step4 takes off -ant, -ence and the like in context <c>vcvc<v>.
+func (z *stemmer) step4() {
+	if z.k == 0 {
+		return
+	}
+	for _, s := range step4Suffixes[z.b[z.k-1]] {
+		if !z.ends(s) {
+			continue
+		}
+		if s == "ion" && (z.j < 0 || z.b[z.j] != 's' && z.b[z.j] != 't') {
+			continue
+		}
+		if z.m() > 1 {
+			z.k = z.j
+		}
+		return
+	}
+}
+
+// step5 removes a final -e if the measure is above 1, and changes -ll to -l
+// if the measure is above 1.
+func (z *stemmer) step5() {
+	z.j = z.k
+	if z.b[z.k] == 'e' {
+		a := z.m()
+		if a > 1 || a == 1 && !z.cvc(z.k-1) {
+			z.k--
+		}
+	}
+	if z.b[z.k] == 'l' && z.doublec(z.k) && z.m() > 1 {
+		z.k--
+	}
+}
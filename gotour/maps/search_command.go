@@ -0,0 +1,136 @@
+package maps
+
+import (
+	"bufio"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+const searchUsage = `usage:
+	search index [-stem] [-stop] -o index file|dir ...
+	search query [-rank bm25|tfidf] [-n n] -i index query ...
+	search stats -i index`
+
+// SearchCommand runs the search command with the given arguments:
+//
+//	search index [-stem] [-stop] -o index file|dir ...
+//
+// indexes the files and the text files in the directories, as described
+// for AddDir, and saves the index to the file index.
+//
+//	search query [-rank bm25|tfidf] [-n n] -i index query ...
+//
+// loads an index saved by "search index" and prints the n best matches for
+// the query, best first, as "score name" lines.
+//
+//	search stats -i index
+//
+// prints the number of documents and terms in an index, and its options.
+func SearchCommand(args []string, stdout io.Writer) error {
+	if len(args) == 0 {
+		return errors.New(searchUsage)
+	}
+	switch args[0] {
+	case "index":
+		return searchIndex(args[1:], stdout)
+	case "query":
+		return searchQuery(args[1:], stdout)
+	case "stats":
+		return searchStats(args[1:], stdout)
+	}
+	return fmt.Errorf("search: unknown command %q\n%s", args[0], searchUsage)
+}
+
+func searchIndex(args []string, stdout io.Writer) error {
+	fs := flag.NewFlagSet("search index", flag.ContinueOnError)
+	var opts IndexOptions
+	fs.BoolVar(&opts.Stem, "stem", false, "index the stems of English words")
+	fs.BoolVar(&opts.Stop, "stop", false, "leave out common English words")
+	out := fs.String("o", "", "write the index to `file`")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *out == "" || fs.NArg() == 0 {
+		return errors.New(searchUsage)
+	}
+
+	idx := NewIndex(opts)
+	for _, path := range fs.Args() {
+		info, err := os.Stat(path)
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			err = idx.AddDir(path)
+		} else {
+			err = idx.AddFile(path)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	if err := idx.Save(*out); err != nil {
+		return err
+	}
+	fmt.Fprintf(stdout, "indexed %d documents, %d terms\n", len(idx.docs), idx.Terms())
+	return nil
+}
+
+func searchQuery(args []string, stdout io.Writer) error {
+	fs := flag.NewFlagSet("search query", flag.ContinueOnError)
+	rank := fs.String("rank", "bm25", "ranking: bm25 or tfidf")
+	n := fs.Int("n", 10, "matches to print; 0 prints all")
+	in := fs.String("i", "", "read the index from `file`")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *in == "" || fs.NArg() == 0 {
+		return errors.New(searchUsage)
+	}
+	var ranking Ranking
+	switch *rank {
+	case "bm25":
+		ranking = BM25
+	case "tfidf":
+		ranking = TFIDF
+	default:
+		return fmt.Errorf("search: unknown ranking %q", *rank)
+	}
+
+	idx, err := LoadIndex(*in)
+	if err != nil {
+		return err
+	}
+	w := bufio.NewWriter(stdout)
+	for _, hit := range idx.Search(strings.Join(fs.Args(), " "), ranking, *n) {
+		fmt.Fprintf(w, "%8.4f %s\n", hit.Score, hit.Name)
+	}
+	return w.Flush()
+}
+
+func searchStats(args []string, stdout io.Writer) error {
+	fs := flag.NewFlagSet("search stats", flag.ContinueOnError)
+	in := fs.String("i", "", "read the index from `file`")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *in == "" {
+		return errors.New(searchUsage)
+	}
+	idx, err := LoadIndex(*in)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(stdout, "documents  %d\n", len(idx.docs))
+	fmt.Fprintf(stdout, "terms      %d\n", idx.Terms())
+	if len(idx.docs) > 0 {
+		fmt.Fprintf(stdout, "length     %d terms, %.1f per document\n", idx.length, float64(idx.length)/float64(len(idx.docs)))
+	}
+	fmt.Fprintf(stdout, "stemmed    %t\n", idx.opts.Stem)
+	fmt.Fprintf(stdout, "stop words %t\n", idx.opts.Stop)
+	return nil
+}
@@ -0,0 +1,236 @@
+package maps
+
+/*
+Word frequencies
+================
+
+WordCount splits a string with strings.Fields, so "The", "the" and "the," are
+three different words. Frequencies counts words the way a reader would:
+
+    segmentation  a word is a run of letters, marks and digits, with
+                  apostrophes inside it ("don't", "o'clock"); every other
+                  character separates words. Ideographs such as 日 are words
+                  on their own, since Chinese and Japanese text does not
+                  separate words with spaces.
+    case folding  "The" and "the" are the same word. Fold maps every
+                  character to the lower case of its upper case, so that
+                  final sigma "ς" and "σ", or the Kelvin sign "K" and "k",
+                  are also the same.
+    stop words    the most common words, such as "the" and "of", say little
+                  about a text and can be left out.
+    stemming      "connected" and "connection" can be counted as "connect"
+                  (see Stem).
+    n-grams       sequences of N words, such as "of the", can be counted
+                  instead of single words. A stop word left out ends the
+                  sequence, so n-grams are made of adjacent words only.
+
+ScanUnicodeWords is a bufio.SplitFunc, so Add reads its input with a
+bufio.Scanner, one block at a time: the size of the input does not matter,
+only the number of distinct words.
+*/
+
+import (
+	"bufio"
+	"cmp"
+	"io"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"gotour/sorting"
+)
+
+// EnglishStopWords are common English words to leave out of counts.
+var EnglishStopWords = StopWords(strings.Fields(`
+	a about above after again against all am an and any are as at be because
+	been before being below between both but by can could did do does doing
+	down during each few for from further had has have having he her here hers
+	herself him himself his how i if in into is it its itself just me more
+	most my myself no nor not now of off on once only or other our ours
+	ourselves out over own same she should so some such than that the their
+	theirs them themselves then there these they this those through to too
+	under until up very was we were what when where which while who whom why
+	will with would you your yours yourself yourselves`))
+
+// StopWords returns the set of the words, case folded.
+func StopWords(words []string) map[string]bool {
+	set := make(map[string]bool, len(words))
+	for _, w := range words {
+		set[Fold(w)] = true
+	}
+	return set
+}
+
+// Fold returns s with every character mapped to the lower case of its upper
+// case.
+func Fold(s string) string {
+	return strings.Map(func(r rune) rune { return unicode.ToLower(unicode.ToUpper(r)) }, s)
+}
+
+// isWordRune reports whether r is part of a word.
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || unicode.IsMark(r)
+}
+
+// isIdeograph reports whether r is a word on its own.
+func isIdeograph(r rune) bool {
+	return unicode.Is(unicode.Ideographic, r)
+}
+
+// isApostrophe reports whether r is an apostrophe, which joins letters.
+func isApostrophe(r rune) bool {
+	return r == '\'' || r == '’'
+}
+
+// ScanUnicodeWords is a bufio.SplitFunc that returns the words of the text
+// as described for Frequencies, without case folding.
+func ScanUnicodeWords(data []byte, atEOF bool) (advance int, token []byte, err error) {
+	// Skip to the start of a word.
+	start := 0
+	for start < len(data) {
+		if !atEOF && !utf8.FullRune(data[start:]) {
+			return start, nil, nil
+		}
+		r, width := utf8.DecodeRune(data[start:])
+		if isWordRune(r) {
+			break
+		}
+		start += width
+	}
+	if start == len(data) {
+		return start, nil, nil
+	}
+	if r, width := utf8.DecodeRune(data[start:]); isIdeograph(r) {
+		return start + width, data[start : start+width], nil
+	}
+
+	// Scan to the end of the word. An apostrophe is part of it if a letter
+	// follows, so we may need to see the next character.
+	for i := start; i < len(data); {
+		if !atEOF && !utf8.FullRune(data[i:]) {
+			return start, nil, nil
+		}
+		r, width := utf8.DecodeRune(data[i:])
+		switch {
+		case isWordRune(r) && !isIdeograph(r):
+			i += width
+			continue
+		case isApostrophe(r) && i > start:
+			next := i + width
+			if next == len(data) && !atEOF || !atEOF && !utf8.FullRune(data[next:]) {
+				return start, nil, nil
+			}
+			if next < len(data) {
+				if r, width := utf8.DecodeRune(data[next:]); unicode.IsLetter(r) && !isIdeograph(r) {
+					i = next + width
+					continue
+				}
+			}
+		}
+		return i, data[start:i], nil
+	}
+	if atEOF {
+		return len(data), data[start:], nil
+	}
+	return start, nil, nil
+}
+
+// WordOptions configure a Frequencies.
+type WordOptions struct {
+	Fold      bool            // count words regardless of case
+	StopWords map[string]bool // case-folded words to leave out
+	Stem      bool            // count the Porter stems of the words
+	N         int             // count sequences of N words; 0 means 1
+}
+
+// A Frequencies counts the words, or the n-grams, of texts.
+type Frequencies struct {
+	opts   WordOptions
+	counts map[string]int
+	total  int
+}
+
+// NewFrequencies returns an empty Frequencies counting as set by opts.
+func NewFrequencies(opts WordOptions) *Frequencies {
+	opts.N = max(opts.N, 1)
+	return &Frequencies{opts: opts, counts: make(map[string]int)}
+}
+
+// normalize returns word folded and stemmed as set by the options, and
+// whether it is to be counted.
+func (f *Frequencies) normalize(word string) (string, bool) {
+	folded := Fold(word)
+	if f.opts.StopWords[folded] {
+		return "", false
+	}
+	if f.opts.Fold {
+		word = folded
+	}
+	if f.opts.Stem {
+		// Stem needs lower case, and stems are lower case anyway.
+		word = Stem(folded)
+	}
+	return word, true
+}
+
+// Add counts the words read from r. N-grams do not span texts added
+// separately, nor the stop words left out: "cat and the cat" has no
+// bigrams without "and" and "the".
+func (f *Frequencies) Add(r io.Reader) error {
+	s := bufio.NewScanner(r)
+	s.Split(ScanUnicodeWords)
+	window := make([]string, 0, f.opts.N)
+	for s.Scan() {
+		word, ok := f.normalize(s.Text())
+		if !ok {
+			window = window[:0]
+			continue
+		}
+		if len(window) == f.opts.N {
+			window = append(window[:0], window[1:]...)
+		}
+		window = append(window, word)
+		if len(window) == f.opts.N {
+			f.counts[strings.Join(window, " ")]++
+			f.total++
+		}
+	}
+	return s.Err()
+}
+
+// Total returns the number of words or n-grams counted.
+func (f *Frequencies) Total() int {
+	return f.total
+}
+
+// Distinct returns the number of distinct words or n-grams counted.
+func (f *Frequencies) Distinct() int {
+	return len(f.counts)
+}
+
+// A WordFrequency is a word or n-gram and the number of times it occurred.
+type WordFrequency struct {
+	Word  string `json:"word"`
+	Count int    `json:"count"`
+}
+
+// Top returns the n most frequent words or n-grams, most frequent first and
+// in lexical order for equal counts. If n is 0 or less, it returns them all.
+func (f *Frequencies) Top(n int) []WordFrequency {
+	all := make([]WordFrequency, 0, len(f.counts))
+	for w, c := range f.counts {
+		all = append(all, WordFrequency{w, c})
+	}
+	order := func(a, b WordFrequency) int {
+		if c := cmp.Compare(b.Count, a.Count); c != 0 {
+			return c
+		}
+		return strings.Compare(a.Word, b.Word)
+	}
+	if n <= 0 || n > len(all) {
+		n = len(all)
+	}
+	// Sort only the n most frequent.
+	sorting.PartialSortFunc(all, n, order)
+	return all[:n]
+}
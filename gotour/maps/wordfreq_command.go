@@ -0,0 +1,96 @@
+package maps
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// WordFreqCommand runs the wordfreq command with args:
+//
+//	wordfreq [-top n] [-ngram n] [-fold=false] [-stop] [-stopwords file]
+//	         [-stem] [file ...]
+//
+// It counts the words, or with -ngram the n-grams, of the files, or of stdin
+// if there are none, and prints the -top most frequent as "count word"
+// lines, followed by the totals. -stop leaves out EnglishStopWords and
+// -stopwords the words of a file, one or more a line.
+func WordFreqCommand(args []string, stdin io.Reader, stdout io.Writer) error {
+	fs := flag.NewFlagSet("wordfreq", flag.ContinueOnError)
+	top := fs.Int("top", 20, "words to print; 0 prints all")
+	n := fs.Int("ngram", 1, "count sequences of `n` words")
+	fold := fs.Bool("fold", true, "count words regardless of case")
+	stop := fs.Bool("stop", false, "leave out common English words")
+	stopFile := fs.String("stopwords", "", "leave out the words of `file`")
+	stem := fs.Bool("stem", false, "count the stems of English words")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *n < 1 {
+		return fmt.Errorf("wordfreq: -ngram must be at least 1, got %d", *n)
+	}
+
+	opts := WordOptions{Fold: *fold, Stem: *stem, N: *n, StopWords: map[string]bool{}}
+	if *stop {
+		for w := range EnglishStopWords {
+			opts.StopWords[w] = true
+		}
+	}
+	if *stopFile != "" {
+		words, err := readStopWords(*stopFile)
+		if err != nil {
+			return err
+		}
+		for w := range words {
+			opts.StopWords[w] = true
+		}
+	}
+
+	freq := NewFrequencies(opts)
+	if fs.NArg() == 0 {
+		if err := freq.Add(stdin); err != nil {
+			return err
+		}
+	}
+	for _, name := range fs.Args() {
+		if err := addFile(freq, name); err != nil {
+			return err
+		}
+	}
+
+	w := bufio.NewWriter(stdout)
+	for _, wf := range freq.Top(*top) {
+		fmt.Fprintf(w, "%7d %s\n", wf.Count, wf.Word)
+	}
+	fmt.Fprintf(w, "%7d total, %d distinct\n", freq.Total(), freq.Distinct())
+	return w.Flush()
+}
+
+// addFile counts the words of the named file.
+func addFile(freq *Frequencies, name string) error {
+	f, err := os.Open(name)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return freq.Add(f)
+}
+
+// readStopWords reads a stop-word list: words separated by white space, with
+// lines starting with '#' ignored.
+func readStopWords(name string) (map[string]bool, error) {
+	data, err := os.ReadFile(name)
+	if err != nil {
+		return nil, err
+	}
+	var words []string
+	for _, line := range strings.Split(string(data), "\n") {
+		if !strings.HasPrefix(strings.TrimSpace(line), "#") {
+			words = append(words, strings.Fields(line)...)
+		}
+	}
+	return StopWords(words), nil
+}
@@ -0,0 +1,204 @@
+package maps
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+func TestStem(t *testing.T) {
+	// Pairs from Porter's paper and his reference vocabulary.
+	for _, tt := range []struct{ word, stem string }{
+		{"caresses", "caress"}, {"ponies", "poni"}, {"ties", "ti"}, {"caress", "caress"},
+		{"cats", "cat"}, {"feed", "feed"}, {"agreed", "agre"}, {"plastered", "plaster"},
+		{"bled", "bled"}, {"motoring", "motor"}, {"sing", "sing"}, {"conflated", "conflat"},
+		{"troubled", "troubl"}, {"sized", "size"}, {"hopping", "hop"}, {"tanned", "tan"},
+		{"falling", "fall"}, {"hissing", "hiss"}, {"fizzed", "fizz"}, {"failing", "fail"},
+		{"filing", "file"}, {"happy", "happi"}, {"sky", "sky"},
+		{"relational", "relat"}, {"conditional", "condit"}, {"rational", "ration"},
+		{"valenci", "valenc"}, {"hesitanci", "hesit"}, {"digitizer", "digit"},
+		{"conformabli", "conform"}, {"radicalli", "radic"}, {"differentli", "differ"},
+		{"vileli", "vile"}, {"analogousli", "analog"}, {"vietnamization", "vietnam"},
+		{"predication", "predic"}, {"operator", "oper"}, {"feudalism", "feudal"},
+		{"decisiveness", "decis"}, {"hopefulness", "hope"}, {"callousness", "callous"},
+		{"formaliti", "formal"}, {"sensitiviti", "sensit"}, {"sensibiliti", "sensibl"},
+		{"triplicate", "triplic"}, {"formative", "form"}, {"formalize", "formal"},
+		{"electriciti", "electr"}, {"electrical", "electr"}, {"hopeful", "hope"},
+		{"goodness", "good"}, {"revival", "reviv"}, {"allowance", "allow"},
+		{"inference", "infer"}, {"airliner", "airlin"}, {"gyroscopic", "gyroscop"},
+		{"adjustable", "adjust"}, {"defensible", "defens"}, {"irritant", "irrit"},
+		{"replacement", "replac"}, {"adjustment", "adjust"}, {"dependent", "depend"},
+		{"adoption", "adopt"}, {"communism", "commun"}, {"activate", "activ"},
+		{"angulariti", "angular"}, {"homologous", "homolog"}, {"effective", "effect"},
+		{"bowdlerize", "bowdler"}, {"probate", "probat"}, {"rate", "rate"},
+		{"cease", "ceas"}, {"controll", "control"}, {"roll", "roll"},
+		{"generalization", "gener"}, {"connection", "connect"}, {"connecting", "connect"},
+		// Short words and words with other characters are left alone.
+		{"is", "is"}, {"café", "café"}, {"r2d2", "r2d2"},
+	} {
+		if got := Stem(tt.word); got != tt.stem {
+			t.Errorf("Stem(%q) = %q; want %q", tt.word, got, tt.stem)
+		}
+	}
+}
+
+// scanWords returns the words that s reads, split by ScanUnicodeWords.
+func scanWords(t *testing.T, s *bufio.Scanner) []string {
+	t.Helper()
+	s.Split(ScanUnicodeWords)
+	var words []string
+	for s.Scan() {
+		words = append(words, s.Text())
+	}
+	if err := s.Err(); err != nil {
+		t.Fatal(err)
+	}
+	return words
+}
+
+func TestScanUnicodeWords(t *testing.T) {
+	for _, tt := range []struct {
+		text  string
+		words []string
+	}{
+		{"", nil},
+		{"  \n\t ", nil},
+		{"The cat, the hat.", []string{"The", "cat", "the", "hat"}},
+		{"Don't stop—it’s 5 o'clock!", []string{"Don't", "stop", "it’s", "5", "o'clock"}},
+		{"'quoted' rock'n'roll cats' 90's", []string{"quoted", "rock'n'roll", "cats", "90's"}},
+		{"日本語のテキスト", []string{"日", "本", "語", "のテキスト"}},
+		{"中文text", []string{"中", "文", "text"}},
+		{"café naïve Ελλάδα", []string{"café", "naïve", "Ελλάδα"}},
+		{"end'", []string{"end"}},
+	} {
+		whole := scanWords(t, bufio.NewScanner(strings.NewReader(tt.text)))
+		if !slices.Equal(whole, tt.words) {
+			t.Errorf("words of %q = %q; want %q", tt.text, whole, tt.words)
+		}
+		// Reading a byte at a time asks for more data in the middle of
+		// every character, word and apostrophe.
+		split := scanWords(t, bufio.NewScanner(iotest.OneByteReader(strings.NewReader(tt.text))))
+		if !slices.Equal(split, tt.words) {
+			t.Errorf("words of %q read a byte at a time = %q; want %q", tt.text, split, tt.words)
+		}
+	}
+}
+
+func TestFold(t *testing.T) {
+	for _, tt := range []struct{ s, want string }{
+		{"The", "the"},
+		{"ΣΟΦΟΣ σοφος", "σοφοσ σοφοσ"}, // final sigma
+		{"\u212a", "k"}, // Kelvin sign
+		{"Straße", "straße"},
+	} {
+		if got := Fold(tt.s); got != tt.want {
+			t.Errorf("Fold(%q) = %q; want %q", tt.s, got, tt.want)
+		}
+	}
+	if !EnglishStopWords["the"] || EnglishStopWords["The"] {
+		t.Error("EnglishStopWords are not case folded")
+	}
+}
+
+// count returns the counts of the texts added to a Frequencies with opts.
+func count(t *testing.T, opts WordOptions, texts ...string) *Frequencies {
+	t.Helper()
+	f := NewFrequencies(opts)
+	for _, text := range texts {
+		if err := f.Add(strings.NewReader(text)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return f
+}
+
+func TestFrequencies(t *testing.T) {
+	for _, tt := range []struct {
+		name  string
+		opts  WordOptions
+		texts []string
+		want  map[string]int
+	}{
+		{"case", WordOptions{}, []string{"The cat saw the cat."},
+			map[string]int{"The": 1, "the": 1, "cat": 2, "saw": 1}},
+		{"fold", WordOptions{Fold: true}, []string{"The cat saw the CAT."},
+			map[string]int{"the": 2, "cat": 2, "saw": 1}},
+		{"stop words", WordOptions{Fold: true, StopWords: EnglishStopWords}, []string{"The cat and THE hat"},
+			map[string]int{"cat": 1, "hat": 1}},
+		{"stem", WordOptions{Stem: true}, []string{"Connected, connecting and connections"},
+			map[string]int{"connect": 3, "and": 1}},
+		{"bigrams", WordOptions{N: 2}, []string{"a b c a b"},
+			map[string]int{"a b": 2, "b c": 1, "c a": 1}},
+		{"trigrams", WordOptions{N: 3}, []string{"a b"}, map[string]int{}},
+		{"n-grams per text", WordOptions{N: 2}, []string{"a b", "c d"},
+			map[string]int{"a b": 1, "c d": 1}},
+		{"n-grams and stop words", WordOptions{N: 2, StopWords: EnglishStopWords}, []string{"cat and the cat"},
+			map[string]int{}},
+		{"n-grams between stop words", WordOptions{N: 2, StopWords: EnglishStopWords},
+			[]string{"big cat and the small dog ran"},
+			map[string]int{"big cat": 1, "small dog": 1, "dog ran": 1}},
+	} {
+		f := count(t, tt.opts, tt.texts...)
+		total := 0
+		for _, n := range tt.want {
+			total += n
+		}
+		if f.Total() != total || f.Distinct() != len(tt.want) {
+			t.Errorf("%s: %d total, %d distinct; want %d, %d", tt.name, f.Total(), f.Distinct(), total, len(tt.want))
+		}
+		for _, wf := range f.Top(0) {
+			if tt.want[wf.Word] != wf.Count {
+				t.Errorf("%s: %q counted %d times; want %d", tt.name, wf.Word, wf.Count, tt.want[wf.Word])
+			}
+		}
+	}
+}
+
+func TestTop(t *testing.T) {
+	f := count(t, WordOptions{}, "d b c a b c d d e")
+	want := []WordFrequency{{"d", 3}, {"b", 2}, {"c", 2}, {"a", 1}, {"e", 1}}
+	if got := f.Top(0); !slices.Equal(got, want) {
+		t.Errorf("Top(0) = %v; want %v", got, want)
+	}
+	if got := f.Top(10); !slices.Equal(got, want) {
+		t.Errorf("Top(10) = %v; want %v", got, want)
+	}
+	for n := 1; n <= len(want); n++ {
+		if got := f.Top(n); !slices.Equal(got, want[:n]) {
+			t.Errorf("Top(%d) = %v; want %v", n, got, want[:n])
+		}
+	}
+}
+
+func TestWordFreqCommand(t *testing.T) {
+	stop := filepath.Join(t.TempDir(), "stop.txt")
+	if err := os.WriteFile(stop, []byte("# common words\nthe a\n  # more\nof\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	for _, tt := range []struct {
+		args []string
+		want string
+	}{
+		{[]string{"-top", "2"}, "      3 the\n      2 cat\n     11 total, 8 distinct\n"},
+		{[]string{"-top", "2", "-fold=false"}, "      2 cat\n      2 the\n     11 total, 9 distinct\n"},
+		{[]string{"-top", "1", "-stopwords", stop}, "      2 cat\n      6 total, 5 distinct\n"},
+		// The stop words leave "cat sat", "mat", "cat" and "dog".
+		{[]string{"-top", "1", "-stop", "-ngram", "2"}, "      1 cat sat\n      1 total, 1 distinct\n"},
+	} {
+		var out strings.Builder
+		in := strings.NewReader("The cat sat on the mat, the cat of a dog.")
+		if err := WordFreqCommand(tt.args, in, &out); err != nil {
+			t.Fatalf("%v: %v", tt.args, err)
+		}
+		if out.String() != tt.want {
+			t.Errorf("%v: output\n%s\nwant\n%s", tt.args, out.String(), tt.want)
+		}
+	}
+	if err := WordFreqCommand([]string{"-ngram", "0"}, strings.NewReader(""), &strings.Builder{}); err == nil {
+		t.Error("-ngram 0: got no error")
+	}
+}
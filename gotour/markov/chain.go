@@ -0,0 +1,309 @@
+/*
+Markov chains of any tokens
+===========================
+
+The Chain in idiomaticgo models text as a sequence of words. The algorithm
+itself does not care what the elements of the sequence are, as long as they
+can be compared: the same model can generate names letter by letter, or
+source code token by token. Chain[T] is the generic version of it, for any
+comparable token type T.
+
+	c := markov.NewChain[rune](3)
+	for _, name := range names {
+		c.Build(slices.Values([]rune(name)))
+	}
+	name := string(slices.Collect(c.Generate(20, rng)))
+
+Tokens and prefixes
+===================
+
+A prefix of the word chain is keyed by its words joined with spaces, which only
+works because words contain no spaces. Chain[T] instead numbers every token it
+sees, and keys a prefix by the numbers of its tokens, encoded as varints in a
+string. Number 0 is not a token: it pads the prefix at the start of a
+sequence, and as a suffix it marks the end of one.
+
+Sequences
+=========
+
+Each call to Build adds one sequence to the chain, from its start to its end.
+Because the end is recorded, a chain built from a list of names generates
+whole names and stops, rather than running into the next one.
+
+Running text has no such end: the last words of a book are no more likely to
+end a text than any others. BuildOpen adds a sequence without its end, so
+that generation runs on past it, as the word chain always has.
+
+Transitions
+===========
+
+All lists the chain as Transitions: a prefix, a suffix and the number of
+times the suffix followed the prefix. A prefix at the start of a sequence has
+fewer than PrefixLen tokens, and a suffix can be the end of the sequence
+rather than a token. Add adds a Transition, so a chain can be saved as its
+list of transitions in any format and loaded again, and Merge adds all the
+transitions of one chain to another.
+*/
+package markov
+
+import (
+	"encoding/binary"
+	"fmt"
+	"iter"
+	"math/rand"
+)
+
+// A Chain is a Markov chain of tokens of type T.
+type Chain[T comparable] struct {
+	prefixLen int
+	tokens    []T       // tokens by number; tokens[0] is unused
+	numbers   map[T]int // number of each token
+	chain     map[string]*suffixes
+}
+
+// NewChain returns a new Chain with prefixes of prefixLen tokens.
+func NewChain[T comparable](prefixLen int) *Chain[T] {
+	return &Chain[T]{
+		prefixLen: prefixLen,
+		tokens:    make([]T, 1),
+		numbers:   make(map[T]int),
+		chain:     make(map[string]*suffixes),
+	}
+}
+
+// PrefixLen returns the number of tokens in the prefixes of c.
+func (c *Chain[T]) PrefixLen() int {
+	return c.prefixLen
+}
+
+// number returns the number of token, numbering it if it is new.
+func (c *Chain[T]) number(token T) int {
+	n, ok := c.numbers[token]
+	if !ok {
+		n = len(c.tokens)
+		c.tokens = append(c.tokens, token)
+		c.numbers[token] = n
+	}
+	return n
+}
+
+// A prefix is a Markov chain prefix of token numbers, with its key.
+type prefix struct {
+	numbers []int
+	key     []byte
+}
+
+func newPrefix(n int) *prefix {
+	return &prefix{numbers: make([]int, n)}
+}
+
+// shift removes the first number from the prefix and appends n.
+func (p *prefix) shift(n int) {
+	if len(p.numbers) > 0 {
+		copy(p.numbers, p.numbers[1:])
+		p.numbers[len(p.numbers)-1] = n
+	}
+}
+
+// bytes returns the key of the prefix in the chain map. It is only valid
+// until the next call.
+func (p *prefix) bytes() []byte {
+	p.key = p.key[:0]
+	for _, n := range p.numbers {
+		p.key = binary.AppendUvarint(p.key, uint64(n))
+	}
+	return p.key
+}
+
+// Build adds the sequence of tokens to the chain, and its end.
+func (c *Chain[T]) Build(tokens iter.Seq[T]) {
+	if p := c.build(tokens); p != nil {
+		c.add(p, 0, 1)
+	}
+}
+
+// BuildOpen adds the sequence of tokens to the chain without its end.
+func (c *Chain[T]) BuildOpen(tokens iter.Seq[T]) {
+	c.build(tokens)
+}
+
+// build adds the tokens to the chain and returns the prefix they end with,
+// or nil if there were none.
+func (c *Chain[T]) build(tokens iter.Seq[T]) *prefix {
+	p := newPrefix(c.prefixLen)
+	empty := true
+	for token := range tokens {
+		n := c.number(token)
+		c.add(p, n, 1)
+		p.shift(n)
+		empty = false
+	}
+	if empty {
+		return nil
+	}
+	return p
+}
+
+// add counts count occurrences of suffix n after p.
+func (c *Chain[T]) add(p *prefix, n, count int) {
+	s := c.chain[string(p.bytes())]
+	if s == nil {
+		s = new(suffixes)
+		c.chain[string(p.key)] = s
+	}
+	s.add(n, count)
+}
+
+// Generate returns a sequence of at most n tokens generated from c with
+// random numbers from rng. It ends early if the chain reaches the end of a
+// sequence it was built from.
+func (c *Chain[T]) Generate(n int, rng *rand.Rand) iter.Seq[T] {
+	return c.GenerateWith(nil, n, rng, Options{})
+}
+
+// GenerateWith is like Generate, but picks the suffixes as set by opts and
+// carries on after the tokens start, as if they had just been generated.
+// The sequence does not include start. It is empty if start has a token the
+// chain has never seen.
+func (c *Chain[T]) GenerateWith(start []T, n int, rng *rand.Rand, opts Options) iter.Seq[T] {
+	return func(yield func(T) bool) {
+		p := newPrefix(c.prefixLen)
+		for _, token := range start {
+			number, ok := c.numbers[token]
+			if !ok {
+				return
+			}
+			p.shift(number)
+		}
+		samplers := make(map[*suffixes]reshaped)
+		for i := 0; i < n; i++ {
+			s := c.chain[string(p.bytes())]
+			if s == nil {
+				return
+			}
+			next := s.pick(rng, opts, samplers)
+			if next == 0 || !yield(c.tokens[next]) {
+				return
+			}
+			p.shift(next)
+		}
+	}
+}
+
+// A Transition is a suffix of a prefix and the number of times it followed
+// the prefix.
+type Transition[T comparable] struct {
+	Prefix []T  // fewer than PrefixLen tokens at the start of a sequence
+	Suffix T    // the zero value if End
+	End    bool // the suffix is the end of the sequence
+	Count  int
+}
+
+// decode returns the token numbers of the prefix key.
+func decode(key string) []int {
+	var numbers []int
+	for b := []byte(key); len(b) > 0; {
+		n, width := binary.Uvarint(b)
+		numbers = append(numbers, int(n))
+		b = b[width:]
+	}
+	return numbers
+}
+
+// All returns the transitions of c. The transitions of a prefix come one
+// after the other, in the order their suffixes first followed it; the
+// prefixes come in no particular order.
+func (c *Chain[T]) All() iter.Seq[Transition[T]] {
+	return func(yield func(Transition[T]) bool) {
+		for key, s := range c.chain {
+			var p []T
+			for _, n := range decode(key) {
+				if n != 0 {
+					p = append(p, c.tokens[n])
+				}
+			}
+			for i, n := range s.numbers {
+				t := Transition[T]{Prefix: p, Suffix: c.tokens[n], End: n == 0, Count: s.counts[i]}
+				if !yield(t) {
+					return
+				}
+			}
+		}
+	}
+}
+
+// Add adds the transition t to c.
+func (c *Chain[T]) Add(t Transition[T]) error {
+	if len(t.Prefix) > c.prefixLen {
+		return fmt.Errorf("markov: prefix of %d tokens in a chain with prefix length %d", len(t.Prefix), c.prefixLen)
+	}
+	if t.Count <= 0 {
+		return fmt.Errorf("markov: transition with count %d", t.Count)
+	}
+	p := newPrefix(c.prefixLen)
+	for _, token := range t.Prefix {
+		p.shift(c.number(token))
+	}
+	n := 0
+	if !t.End {
+		n = c.number(t.Suffix)
+	}
+	c.add(p, n, t.Count)
+	return nil
+}
+
+// Merge adds the transitions of other to c. Both must have the same prefix
+// length. The suffixes new to a prefix of c are added in the order they
+// first followed it in other, so merging the chains of several sequences
+// in turn gives the same chain as building them in turn.
+func (c *Chain[T]) Merge(other *Chain[T]) error {
+	if c.prefixLen != other.prefixLen {
+		return fmt.Errorf("markov: cannot merge prefix length %d into %d", other.prefixLen, c.prefixLen)
+	}
+	numbers := make([]int, len(other.tokens)) // numbers in c of the tokens of other
+	for n := 1; n < len(other.tokens); n++ {
+		numbers[n] = c.number(other.tokens[n])
+	}
+	p := newPrefix(c.prefixLen)
+	for key, s := range other.chain {
+		for i, n := range decode(key) {
+			p.numbers[i] = numbers[n]
+		}
+		for i, n := range s.numbers {
+			c.add(p, numbers[n], s.counts[i])
+		}
+	}
+	return nil
+}
+
+// Count returns the number of times suffix followed prefix, and the number
+// of times prefix was followed by anything. prefix has fewer than PrefixLen
+// tokens only at the start of a sequence.
+func (c *Chain[T]) Count(prefix []T, suffix T) (count, total int) {
+	s := c.lookup(prefix)
+	if s == nil {
+		return 0, 0
+	}
+	if n, ok := c.numbers[suffix]; ok {
+		if i, ok := s.find(n); ok {
+			count = s.counts[i]
+		}
+	}
+	return count, s.total
+}
+
+// lookup returns the suffixes of prefix, or nil if there are none.
+func (c *Chain[T]) lookup(prefix []T) *suffixes {
+	if len(prefix) > c.prefixLen {
+		prefix = prefix[len(prefix)-c.prefixLen:]
+	}
+	p := newPrefix(c.prefixLen)
+	for _, token := range prefix {
+		n, ok := c.numbers[token]
+		if !ok {
+			return nil
+		}
+		p.shift(n)
+	}
+	return c.chain[string(p.bytes())]
+}
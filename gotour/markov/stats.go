@@ -0,0 +1,134 @@
+package markov
+
+/*
+Inspecting a chain
+==================
+
+Stats summarizes a Chain:
+
+    prefixes   the number of distinct prefixes
+    pairs      the number of distinct prefix and suffix pairs
+    tokens     the number of transitions the chain was built from: the
+               tokens of its sequences, plus one for the end of each
+               sequence added by Build
+    branching  how many prefixes have 1, 2, 3, ... distinct suffixes; a
+               chain whose prefixes mostly have a single suffix can only
+               repeat its corpus
+    entropy    the uncertainty of the next token given the prefix, in bits,
+               averaged over the prefixes and weighted by how often each
+               occurs; Entropy gives it for a single prefix
+
+Perplexity
+==========
+
+The perplexity of a model on a sequence measures how surprised the model is
+by it: it is 2 to the power of the average number of bits needed per token,
+or the number of equally likely tokens the model is choosing between on
+average. Measured on held-out text, text the chain was not built from, it
+shows how well the model generalizes. A longer prefix always lowers the
+entropy on the training text, but eventually raises the perplexity on other
+text.
+
+A chain gives probability 0 to every token it has not seen after a prefix,
+and a single such token makes the perplexity infinite. Add-k smoothing
+pretends that every token of the vocabulary, plus one for unknown tokens, was
+seen k more times after every prefix:
+
+	P(token | prefix) = (count(prefix, token) + k) / (count(prefix) + k*V)
+
+where V is the size of the vocabulary plus one. With k = 0 the probabilities
+are the chain's own.
+*/
+
+import (
+	"errors"
+	"fmt"
+	"iter"
+	"math"
+)
+
+// Stats summarizes a Chain.
+type Stats struct {
+	Prefixes  int         // distinct prefixes
+	Pairs     int         // distinct prefix and suffix pairs
+	Tokens    int         // transitions the chain was built from
+	Branching map[int]int // number of prefixes by number of distinct suffixes
+	Entropy   float64     // bits per token, weighted by prefix frequency
+}
+
+// MeanBranching returns the average number of distinct suffixes of a prefix.
+func (s Stats) MeanBranching() float64 {
+	if s.Prefixes == 0 {
+		return 0
+	}
+	return float64(s.Pairs) / float64(s.Prefixes)
+}
+
+// Stats returns the statistics of c.
+func (c *Chain[T]) Stats() Stats {
+	st := Stats{Prefixes: len(c.chain), Branching: make(map[int]int)}
+	for _, s := range c.chain {
+		st.Pairs += len(s.numbers)
+		st.Tokens += s.total
+		st.Branching[len(s.numbers)]++
+		st.Entropy += float64(s.total) * s.entropy()
+	}
+	if st.Tokens > 0 {
+		st.Entropy /= float64(st.Tokens)
+	}
+	return st
+}
+
+// Entropy returns the entropy in bits of the suffixes of prefix, or 0 if
+// the chain has no such prefix.
+func (c *Chain[T]) Entropy(prefix []T) float64 {
+	s := c.lookup(prefix)
+	if s == nil {
+		return 0
+	}
+	return s.entropy()
+}
+
+// vocabulary returns the number of distinct tokens that are suffixes in c.
+func (c *Chain[T]) vocabulary() int {
+	seen := make(map[int]bool)
+	for _, s := range c.chain {
+		for _, n := range s.numbers {
+			if n != 0 {
+				seen[n] = true
+			}
+		}
+	}
+	return len(seen)
+}
+
+// Perplexity returns the perplexity of c on the sequence of tokens, with
+// add-k smoothing. It returns +Inf if k is 0 and the sequence has a token
+// the chain cannot generate after its prefix.
+func (c *Chain[T]) Perplexity(tokens iter.Seq[T], k float64) (float64, error) {
+	if k < 0 {
+		return 0, fmt.Errorf("markov: negative smoothing %g", k)
+	}
+	v := float64(c.vocabulary() + 1)
+	var prefix []T
+	bits, n := 0.0, 0
+	for token := range tokens {
+		count, total := c.Count(prefix, token)
+		prob := 0.0
+		if denom := float64(total) + k*v; denom > 0 {
+			prob = (float64(count) + k) / denom
+		}
+		bits -= math.Log2(prob)
+		n++
+		if len(prefix) == c.prefixLen {
+			prefix = append(prefix[:0], prefix[1:]...)
+		}
+		if c.prefixLen > 0 {
+			prefix = append(prefix, token)
+		}
+	}
+	if n == 0 {
+		return 0, errors.New("markov: no tokens to evaluate")
+	}
+	return math.Exp2(bits / float64(n)), nil
+}
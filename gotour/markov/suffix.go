@@ -0,0 +1,233 @@
+package markov
+
+/*
+Counting suffixes
+=================
+
+A list holding every occurrence of every suffix grows with the corpus: after
+reading a book, the list for "of the" holds thousands of words, most of them
+repeated. A suffixes table stores each distinct suffix once, with the number
+of times it occurred. Picking a suffix with probability proportional to its
+count gives exactly the same distribution as picking a random element of the
+list.
+
+The alias method
+================
+
+Picking from a weighted distribution by walking the cumulative counts takes
+time proportional to the number of suffixes. Vose's alias method takes
+constant time after a linear setup: the n weights are split into n buckets of
+equal probability, each holding at most two suffixes, the bucket's own and an
+"alias". To sample, we pick a bucket uniformly and then one of its two
+suffixes with the bucket's probability.
+
+Temperature, top-k and top-p
+============================
+
+Options can reshape the distribution before sampling:
+
+    Temperature  each count c is replaced by c^(1/Temperature); below 1 the
+                 common suffixes become more likely, above 1 the rare ones
+    TopK         only the TopK most frequent suffixes are kept
+    TopP         only the most frequent suffixes whose probabilities add up
+                 to at least TopP are kept
+
+With the zero Options (or Temperature 1) the counts are used as they are, and
+each table caches its alias table for them. The cache is filled at most once,
+under a sync.Once, so any number of goroutines can generate from one chain at
+the same time; only adding to the chain must not happen concurrently.
+*/
+
+import (
+	"math"
+	"math/rand"
+	"sort"
+	"sync"
+)
+
+// suffixIndexMin is the number of suffixes above which a suffixes table
+// keeps an index of its numbers rather than searching them.
+const suffixIndexMin = 16
+
+// suffixes counts the suffixes of a prefix by token number, in the order
+// they first occurred.
+type suffixes struct {
+	numbers []int
+	counts  []int
+	total   int
+	index   map[int]int // position of each number, once there are many
+
+	aliasOnce sync.Once   // guards alias
+	alias     *aliasTable // for sampling the counts as they are
+}
+
+// add counts count more occurrences of suffix n.
+func (s *suffixes) add(n, count int) {
+	s.aliasOnce, s.alias = sync.Once{}, nil
+	s.total += count
+	if i, ok := s.find(n); ok {
+		s.counts[i] += count
+		return
+	}
+	s.numbers = append(s.numbers, n)
+	s.counts = append(s.counts, count)
+	if s.index != nil {
+		s.index[n] = len(s.numbers) - 1
+	} else if len(s.numbers) > suffixIndexMin {
+		s.index = make(map[int]int, len(s.numbers))
+		for i, n := range s.numbers {
+			s.index[n] = i
+		}
+	}
+}
+
+// find returns the position of suffix n.
+func (s *suffixes) find(n int) (int, bool) {
+	if s.index != nil {
+		i, ok := s.index[n]
+		return i, ok
+	}
+	for i, m := range s.numbers {
+		if m == n {
+			return i, true
+		}
+	}
+	return 0, false
+}
+
+// entropy returns the entropy of the suffixes in bits.
+func (s *suffixes) entropy() float64 {
+	h := 0.0
+	for _, c := range s.counts {
+		p := float64(c) / float64(s.total)
+		h -= p * math.Log2(p)
+	}
+	return h
+}
+
+// Options reshape the distribution of the suffixes of a prefix before one
+// is picked. The zero value picks suffixes in proportion to their counts.
+type Options struct {
+	Temperature float64 // sharpen (< 1) or flatten (> 1) the distribution; 0 means 1
+	TopK        int     // keep only the TopK most frequent suffixes; 0 keeps all
+	TopP        float64 // keep the most frequent suffixes covering TopP of the probability; 0 keeps all
+}
+
+// plain reports whether o leaves the counts unchanged.
+func (o Options) plain() bool {
+	return (o.Temperature == 0 || o.Temperature == 1) && o.TopK <= 0 && (o.TopP <= 0 || o.TopP >= 1)
+}
+
+// sampler returns an alias table for the suffixes reshaped by o, and the
+// positions in s.numbers of the suffixes it samples.
+func (s *suffixes) sampler(o Options) (*aliasTable, []int) {
+	if o.plain() {
+		s.aliasOnce.Do(func() {
+			weights := make([]float64, len(s.counts))
+			for i, c := range s.counts {
+				weights[i] = float64(c)
+			}
+			s.alias = newAliasTable(weights)
+		})
+		return s.alias, nil
+	}
+
+	order := make([]int, len(s.numbers))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool { return s.counts[order[a]] > s.counts[order[b]] })
+	if o.TopK > 0 && o.TopK < len(order) {
+		order = order[:o.TopK]
+	}
+	weights := make([]float64, len(order))
+	sum := 0.0
+	for k, i := range order {
+		weights[k] = float64(s.counts[i])
+		if o.Temperature > 0 {
+			weights[k] = math.Pow(weights[k], 1/o.Temperature)
+		}
+		sum += weights[k]
+	}
+	if o.TopP > 0 && o.TopP < 1 {
+		covered := 0.0
+		for k, w := range weights {
+			covered += w
+			if covered >= o.TopP*sum {
+				order, weights = order[:k+1], weights[:k+1]
+				break
+			}
+		}
+	}
+	return newAliasTable(weights), order
+}
+
+// pick returns the number of a random suffix, reshaped by o. samplers caches
+// the samplers built for o, which is worthwhile within a single generation.
+func (s *suffixes) pick(rng *rand.Rand, o Options, samplers map[*suffixes]reshaped) int {
+	if o.plain() {
+		a, _ := s.sampler(o)
+		return s.numbers[a.sample(rng)]
+	}
+	r, ok := samplers[s]
+	if !ok {
+		r.alias, r.order = s.sampler(o)
+		samplers[s] = r
+	}
+	return s.numbers[r.order[r.alias.sample(rng)]]
+}
+
+// reshaped is a sampler for a reshaped distribution.
+type reshaped struct {
+	alias *aliasTable
+	order []int
+}
+
+// An aliasTable samples indexes in proportion to fixed weights.
+type aliasTable struct {
+	prob  []float64 // probability of keeping a bucket's own index
+	alias []int     // the other index in each bucket
+}
+
+// newAliasTable builds the alias table of weights by Vose's method.
+func newAliasTable(weights []float64) *aliasTable {
+	n := len(weights)
+	a := &aliasTable{prob: make([]float64, n), alias: make([]int, n)}
+	sum := 0.0
+	for _, w := range weights {
+		sum += w
+	}
+	scaled := make([]float64, n)
+	var small, large []int
+	for i, w := range weights {
+		scaled[i] = w * float64(n) / sum
+		if scaled[i] < 1 {
+			small = append(small, i)
+		} else {
+			large = append(large, i)
+		}
+	}
+	for len(small) > 0 && len(large) > 0 {
+		s, l := small[len(small)-1], large[len(large)-1]
+		small = small[:len(small)-1]
+		a.prob[s], a.alias[s] = scaled[s], l
+		scaled[l] -= 1 - scaled[s]
+		if scaled[l] < 1 {
+			small, large = append(small, l), large[:len(large)-1]
+		}
+	}
+	// What is left is 1 up to rounding errors.
+	for _, i := range append(small, large...) {
+		a.prob[i] = 1
+	}
+	return a
+}
+
+// sample returns a random index.
+func (a *aliasTable) sample(rng *rand.Rand) int {
+	i := rng.Intn(len(a.prob))
+	if rng.Float64() < a.prob[i] {
+		return i
+	}
+	return a.alias[i]
+}
@@ -0,0 +1,217 @@
+package markov
+
+/*
+Tokenizers
+==========
+
+To build a Chain[string] from text, the text has to be split into tokens, and
+to turn generated tokens back into text they have to be joined again. A
+Tokenizer does both. Its Split method is a bufio.SplitFunc, so a
+bufio.Scanner does the reading and buffering and reports any read error.
+
+    Word   white-space separated words, joined with spaces; this is how the
+           word chain in idiomaticgo reads its input
+    Char   single characters, white space included, joined as they are
+    Punct  runs of letters, digits and underscores, and every other
+           non-space character on its own, so that "Hello, world!" is
+           "Hello" "," "world" "!"; suited to prose and to source code
+    NGram  runs of N characters, white space included, joined as they are
+
+BuildText adds a whole text to a chain as a single sequence, and BuildLines
+adds every line as a sequence of its own, as for a list of names.
+GenerateText generates tokens and joins them.
+
+NewTokenizer returns a tokenizer by name, and TokenizerName gives the name
+back, so that a saved model can record how its text was split.
+*/
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"iter"
+	"math/rand"
+	"slices"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// A Tokenizer splits text into tokens and joins them again.
+type Tokenizer interface {
+	// Split is a bufio.SplitFunc returning the next token.
+	Split(data []byte, atEOF bool) (advance int, token []byte, err error)
+
+	// Join turns tokens back into text.
+	Join(tokens []string) string
+}
+
+// Word splits text into white-space separated words.
+type Word struct{}
+
+func (Word) Split(data []byte, atEOF bool) (int, []byte, error) {
+	return bufio.ScanWords(data, atEOF)
+}
+
+func (Word) Join(tokens []string) string {
+	return strings.Join(tokens, " ")
+}
+
+// Char splits text into characters.
+type Char struct{}
+
+func (Char) Split(data []byte, atEOF bool) (int, []byte, error) {
+	return bufio.ScanRunes(data, atEOF)
+}
+
+func (Char) Join(tokens []string) string {
+	return strings.Join(tokens, "")
+}
+
+// Punct splits text into words and punctuation.
+type Punct struct{}
+
+// isWordRune reports whether r is part of a word for Punct.
+func isWordRune(r rune) bool {
+	return r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+func (Punct) Split(data []byte, atEOF bool) (int, []byte, error) {
+	// Skip leading spaces.
+	start := 0
+	for start < len(data) {
+		r, width := utf8.DecodeRune(data[start:])
+		if !unicode.IsSpace(r) {
+			break
+		}
+		start += width
+	}
+	if start == len(data) {
+		return start, nil, nil
+	}
+	if !atEOF && !utf8.FullRune(data[start:]) {
+		return start, nil, nil
+	}
+	r, width := utf8.DecodeRune(data[start:])
+	if !isWordRune(r) {
+		return start + width, data[start : start+width], nil
+	}
+	// Scan until the end of the word.
+	for i := start + width; i < len(data); i += width {
+		if !atEOF && !utf8.FullRune(data[i:]) {
+			return start, nil, nil
+		}
+		r, width = utf8.DecodeRune(data[i:])
+		if !isWordRune(r) {
+			return i, data[start:i], nil
+		}
+	}
+	if atEOF {
+		return len(data), data[start:], nil
+	}
+	return start, nil, nil
+}
+
+// Join separates the tokens by spaces, except before closing punctuation
+// and after opening punctuation.
+func (Punct) Join(tokens []string) string {
+	var b strings.Builder
+	for i, t := range tokens {
+		if i > 0 && !strings.Contains(".,;:!?)]}", t) && !strings.Contains("([{", tokens[i-1]) {
+			b.WriteByte(' ')
+		}
+		b.WriteString(t)
+	}
+	return b.String()
+}
+
+// NGram splits text into runs of N characters. N less than 1 means 1.
+type NGram struct {
+	N int
+}
+
+func (g NGram) Split(data []byte, atEOF bool) (int, []byte, error) {
+	i := 0
+	for n := 0; n < max(g.N, 1); n++ {
+		if i == len(data) || !atEOF && !utf8.FullRune(data[i:]) {
+			if atEOF && i > 0 {
+				break
+			}
+			return 0, nil, nil
+		}
+		_, width := utf8.DecodeRune(data[i:])
+		i += width
+	}
+	return i, data[:i], nil
+}
+
+func (NGram) Join(tokens []string) string {
+	return strings.Join(tokens, "")
+}
+
+// NewTokenizer returns the tokenizer called name: "word", "char", "punct",
+// or "ngram" with n characters per token.
+func NewTokenizer(name string, n int) (Tokenizer, error) {
+	switch name {
+	case "word":
+		return Word{}, nil
+	case "char":
+		return Char{}, nil
+	case "punct":
+		return Punct{}, nil
+	case "ngram":
+		return NGram{N: max(n, 1)}, nil
+	}
+	return nil, fmt.Errorf("markov: unknown tokenizer %q", name)
+}
+
+// TokenizerName returns the name and n for which NewTokenizer returns t. It
+// reports false if t is not one of the tokenizers of this package.
+func TokenizerName(t Tokenizer) (name string, n int, ok bool) {
+	switch t := t.(type) {
+	case Word:
+		return "word", 0, true
+	case Char:
+		return "char", 0, true
+	case Punct:
+		return "punct", 0, true
+	case NGram:
+		return "ngram", max(t.N, 1), true
+	}
+	return "", 0, false
+}
+
+// Tokens returns the tokens of r split by t, and a function returning the
+// first read error once the tokens have been read.
+func Tokens(r io.Reader, t Tokenizer) (iter.Seq[string], func() error) {
+	s := bufio.NewScanner(r)
+	s.Split(t.Split)
+	return func(yield func(string) bool) {
+		for s.Scan() && yield(s.Text()) {
+		}
+	}, s.Err
+}
+
+// BuildText adds the text read from r, split by t, to c as one sequence.
+func BuildText(c *Chain[string], r io.Reader, t Tokenizer) error {
+	seq, err := Tokens(r, t)
+	c.Build(seq)
+	return err()
+}
+
+// BuildLines adds every non-empty line read from r, split by t, to c as a
+// sequence of its own.
+func BuildLines(c *Chain[string], r io.Reader, t Tokenizer) error {
+	lines := bufio.NewScanner(r)
+	for lines.Scan() {
+		if err := BuildText(c, strings.NewReader(lines.Text()), t); err != nil {
+			return err
+		}
+	}
+	return lines.Err()
+}
+
+// GenerateText generates at most n tokens from c and joins them with t.
+func GenerateText(c *Chain[string], t Tokenizer, n int, rng *rand.Rand) string {
+	return t.Join(slices.Collect(c.Generate(n, rng)))
+}
@@ -0,0 +1,187 @@
+/*
+Searching sorted data
+=====================
+
+sort.Search finds the smallest index i in [0, n) at which a monotone predicate
+f(i) becomes true. Package search builds on the same idea:
+
+	LowerBound   the first index whose element is not less than x: where x
+	             is, or where it would be inserted before its equals
+	UpperBound   the first index whose element is greater than x: where x
+	             would be inserted after its equals
+	EqualRange   both, the bounds of the elements equal to x
+
+Each has a form for cmp.Ordered elements and one, ending in Func, taking a
+comparison function, with the same arguments as slices.BinarySearchFunc.
+
+Galloping
+=========
+
+Binary search needs to know where the data ends. Exponential search does not:
+it probes indexes 1, 2, 4, 8, ... until the predicate becomes true, and then
+binary searches between the last two probes. It takes time logarithmic in the
+answer rather than in the length of the data, which makes it suitable for
+unbounded sequences and for answers known to be near the start.
+
+Interpolation
+=============
+
+Looking up "Smith" in a phone book, we open it near the end, not in the
+middle. Interpolation search does the same for uniformly distributed numbers:
+it probes where x would be if the values grew linearly from the first to the
+last element. On uniform data it takes about log log n probes instead of
+log n. On skewed data interpolation can make little progress, so every probe
+that fails to halve the range is followed by a plain bisection step, which
+keeps the worst case logarithmic.
+
+Bisection over floats
+=====================
+
+Bisect searches a monotone predicate over real numbers, such as "does the
+loan get paid off with this interest rate?": it halves an interval around the
+point where the predicate becomes true until the interval is within a
+tolerance.
+*/
+package search
+
+import (
+	"cmp"
+	"errors"
+	"math"
+	"sort"
+)
+
+// LowerBound returns the smallest index i such that s[i] >= x, or len(s) if
+// there is none. s must be sorted in increasing order.
+func LowerBound[S ~[]E, E cmp.Ordered](s S, x E) int {
+	return LowerBoundFunc(s, x, cmp.Compare[E])
+}
+
+// LowerBoundFunc is like LowerBound but uses cmp, which returns a negative
+// number if an element is less than the target, to order the elements.
+func LowerBoundFunc[S ~[]E, E, T any](s S, target T, cmp func(E, T) int) int {
+	return sort.Search(len(s), func(i int) bool { return cmp(s[i], target) >= 0 })
+}
+
+// UpperBound returns the smallest index i such that s[i] > x, or len(s) if
+// there is none. s must be sorted in increasing order.
+func UpperBound[S ~[]E, E cmp.Ordered](s S, x E) int {
+	return UpperBoundFunc(s, x, cmp.Compare[E])
+}
+
+// UpperBoundFunc is like UpperBound but orders the elements with cmp.
+func UpperBoundFunc[S ~[]E, E, T any](s S, target T, cmp func(E, T) int) int {
+	return sort.Search(len(s), func(i int) bool { return cmp(s[i], target) > 0 })
+}
+
+// EqualRange returns the bounds [lo, hi) of the elements of s equal to x.
+// If there are none, lo == hi is where x would be inserted.
+func EqualRange[S ~[]E, E cmp.Ordered](s S, x E) (lo, hi int) {
+	return EqualRangeFunc(s, x, cmp.Compare[E])
+}
+
+// EqualRangeFunc is like EqualRange but orders the elements with cmp.
+func EqualRangeFunc[S ~[]E, E, T any](s S, target T, cmp func(E, T) int) (lo, hi int) {
+	lo = LowerBoundFunc(s, target, cmp)
+	hi = lo + UpperBoundFunc(s[lo:], target, cmp)
+	return lo, hi
+}
+
+// Exponential returns the smallest index i >= 0 at which f(i) is true, for
+// f false up to some index and true from then on, without an upper bound on
+// i. It panics if f is false for every index up to math.MaxInt.
+func Exponential(f func(int) bool) int {
+	if f(0) {
+		return 0
+	}
+	lo, hi := 0, 1 // f(lo) is false
+	for !f(hi) {
+		if hi > math.MaxInt/2 {
+			if f(math.MaxInt) {
+				lo, hi = hi, math.MaxInt
+				break
+			}
+			panic("search: predicate is never true")
+		}
+		lo, hi = hi, 2*hi
+	}
+	// f(lo) is false and f(hi) is true.
+	return lo + 1 + sort.Search(hi-lo-1, func(i int) bool { return f(lo + 1 + i) })
+}
+
+// ExponentialSearch is like slices.BinarySearch but gallops from the start
+// of s, so it is faster when x is near the start.
+func ExponentialSearch[S ~[]E, E cmp.Ordered](s S, x E) (int, bool) {
+	i := Exponential(func(i int) bool { return i >= len(s) || s[i] >= x })
+	return i, i < len(s) && s[i] == x
+}
+
+// A Number is an integer or floating-point type.
+type Number interface {
+	~int | ~int8 | ~int16 | ~int32 | ~int64 |
+		~uint | ~uint8 | ~uint16 | ~uint32 | ~uint64 | ~uintptr |
+		~float32 | ~float64
+}
+
+// Interpolation searches for x in s, sorted in increasing order, and returns
+// the position where x is found, or where it would be inserted, and whether
+// it was found, as slices.BinarySearch does. It is fastest when the values
+// of s are uniformly distributed.
+func Interpolation[S ~[]E, E Number](s S, x E) (int, bool) {
+	lo, hi := 0, len(s) // the answer is in [lo, hi]
+	bisect := false
+	for lo < hi {
+		var mid int
+		if !bisect && s[lo] < s[hi-1] && s[lo] <= x && x <= s[hi-1] {
+			// Estimate with floats, which cannot overflow.
+			frac := (float64(x) - float64(s[lo])) / (float64(s[hi-1]) - float64(s[lo]))
+			mid = lo + int(frac*float64(hi-1-lo))
+			mid = min(max(mid, lo), hi-1)
+		} else {
+			mid = lo + (hi-lo)/2
+		}
+		size := hi - lo
+		if s[mid] < x {
+			lo = mid + 1
+		} else {
+			hi = mid
+		}
+		bisect = !bisect && 2*(hi-lo) > size
+	}
+	return lo, lo < len(s) && s[lo] == x
+}
+
+// ErrNotBracketed is returned by Bisect when the predicate is false at the
+// upper end of the interval.
+var ErrNotBracketed = errors.New("search: predicate is false over the whole interval")
+
+// Bisect returns the point in [lo, hi] at which f, false below some point
+// and true from then on, becomes true, to within tol. More precisely, it
+// returns an x with f(x) true and f(x - tol) false, or lo if f(lo) is true.
+// With tol 0, it continues until lo and hi are adjacent floats.
+func Bisect(lo, hi, tol float64, f func(float64) bool) (float64, error) {
+	if lo > hi || math.IsNaN(lo) || math.IsNaN(hi) || tol < 0 {
+		return 0, errors.New("search: invalid interval or tolerance")
+	}
+	if !f(hi) {
+		return 0, ErrNotBracketed
+	}
+	if f(lo) {
+		return lo, nil
+	}
+	// f(lo) is false and f(hi) is true.
+	for hi-lo > tol {
+		// hi-lo overflows for a wide interval such as [-MaxFloat64,
+		// MaxFloat64], but the sum of the halves cannot.
+		mid := lo/2 + hi/2
+		if mid == lo || mid == hi {
+			break // adjacent floats
+		}
+		if f(mid) {
+			hi = mid
+		} else {
+			lo = mid
+		}
+	}
+	return hi, nil
+}
@@ -0,0 +1,31 @@
+package search
+
+import (
+	"math"
+	"testing"
+)
+
+func TestBisect(t *testing.T) {
+	tests := []struct {
+		lo, hi, tol, at float64
+	}{
+		{0, 10, 0, math.Pi},
+		{-math.MaxFloat64, math.MaxFloat64, 0, 0},
+		{-math.MaxFloat64, math.MaxFloat64, 0, 1e300},
+		{-math.MaxFloat64, math.MaxFloat64, 1, -12345.5},
+		{-1, 1, 0, -1},
+	}
+	for _, tt := range tests {
+		x, err := Bisect(tt.lo, tt.hi, tt.tol, func(x float64) bool { return x >= tt.at })
+		if err != nil {
+			t.Errorf("Bisect(%g, %g, %g) at %g: %v", tt.lo, tt.hi, tt.tol, tt.at, err)
+			continue
+		}
+		if x < tt.at || x-tt.at > math.Max(tt.tol, math.Abs(tt.at)*1e-15) {
+			t.Errorf("Bisect(%g, %g, %g) = %g, want %g", tt.lo, tt.hi, tt.tol, x, tt.at)
+		}
+	}
+	if _, err := Bisect(0, 1, 0, func(float64) bool { return false }); err != ErrNotBracketed {
+		t.Errorf("Bisect of a predicate that is always false: got %v, want ErrNotBracketed", err)
+	}
+}
@@ -0,0 +1,364 @@
+package sorting
+
+/*
+Sorting algorithms
+==================
+
+sort.Sort hides the algorithm behind sort.Interface: it only ever calls Len,
+Less and Swap. The functions below sort through the same interface, so they
+can be compared with each other, and with sort.Sort, on equal terms:
+
+    InsertionSort  insert each element into the sorted elements before it;
+                   quadratic, but fast for short or nearly sorted data
+    MergeSort      sort both halves and merge them; stable
+    HeapSort       build a max-heap and move its root to the end repeatedly;
+                   never worse than n log n
+    QuickSort      partition around a median-of-three pivot into elements
+                   less than, equal to and greater than it (3-way), so that
+                   repeated values are handled in one pass
+    RadixSort      distribute the elements into 256 buckets by the most
+                   significant byte of their key, then sort each bucket by the
+                   next byte (American flag sort); needs a RadixInterface
+    TimSort        find the runs already in order, extend short runs with an
+                   insertion sort and merge runs of similar length; linear on
+                   sorted data, stable
+
+Since the data can only be moved by swapping, the merges are done in place by
+rotations, with the SymMerge algorithm that sort.Stable also uses, and TimSort
+does without the galloping mode that needs a temporary buffer.
+*/
+
+import "sort"
+
+// A RadixInterface is a sort.Interface whose elements have unsigned integer
+// keys, ordered as Less orders the elements.
+type RadixInterface interface {
+	sort.Interface
+	Key(i int) uint64
+}
+
+// InsertionSort sorts data with an insertion sort.
+func InsertionSort(data sort.Interface) {
+	insertionSortRange(data, 0, data.Len())
+}
+
+func insertionSortRange(data sort.Interface, lo, hi int) {
+	for i := lo + 1; i < hi; i++ {
+		for j := i; j > lo && data.Less(j, j-1); j-- {
+			data.Swap(j, j-1)
+		}
+	}
+}
+
+// MergeSort sorts data stably with a top-down merge sort.
+func MergeSort(data sort.Interface) {
+	mergeSortRange(data, 0, data.Len())
+}
+
+func mergeSortRange(data sort.Interface, lo, hi int) {
+	if hi-lo <= insertionSortMax {
+		insertionSortRange(data, lo, hi)
+		return
+	}
+	mid := lo + (hi-lo)/2
+	mergeSortRange(data, lo, mid)
+	mergeSortRange(data, mid, hi)
+	symMerge(data, lo, mid, hi)
+}
+
+// symMerge merges the sorted data[lo:mid] and data[mid:hi] in place, as in
+// Kim and Kutzner's SymMerge.
+func symMerge(data sort.Interface, lo, mid, hi int) {
+	if lo >= mid || mid >= hi || !data.Less(mid, mid-1) {
+		return // one side is empty or they are already in order
+	}
+	if mid-lo == 1 {
+		// Insert data[lo] into data[mid:hi] by binary search.
+		i := mid + sort.Search(hi-mid, func(k int) bool { return !data.Less(mid+k, lo) })
+		for k := lo; k < i-1; k++ {
+			data.Swap(k, k+1)
+		}
+		return
+	}
+	if hi-mid == 1 {
+		// Insert data[mid] into data[lo:mid] by binary search.
+		i := lo + sort.Search(mid-lo, func(k int) bool { return data.Less(mid, lo+k) })
+		for k := mid; k > i; k-- {
+			data.Swap(k, k-1)
+		}
+		return
+	}
+
+	m := lo + (hi-lo)/2
+	n := m + mid
+	var start, r int
+	if mid > m {
+		start, r = n-hi, m
+	} else {
+		start, r = lo, mid
+	}
+	p := n - 1
+	for start < r {
+		c := start + (r-start)/2
+		if !data.Less(p-c, c) {
+			start = c + 1
+		} else {
+			r = c
+		}
+	}
+	end := n - start
+	if start < mid && mid < end {
+		rotate(data, start, mid, end)
+	}
+	if lo < start && start < m {
+		symMerge(data, lo, start, m)
+	}
+	if m < end && end < hi {
+		symMerge(data, m, end, hi)
+	}
+}
+
+// rotate swaps the blocks data[a:m] and data[m:b].
+func rotate(data sort.Interface, a, m, b int) {
+	reverseRange(data, a, m)
+	reverseRange(data, m, b)
+	reverseRange(data, a, b)
+}
+
+func reverseRange(data sort.Interface, lo, hi int) {
+	for i, j := lo, hi-1; i < j; i, j = i+1, j-1 {
+		data.Swap(i, j)
+	}
+}
+
+// HeapSort sorts data with a heap sort.
+func HeapSort(data sort.Interface) {
+	n := data.Len()
+	for i := n/2 - 1; i >= 0; i-- {
+		siftDown(data, i, n)
+	}
+	for end := n - 1; end > 0; end-- {
+		data.Swap(0, end)
+		siftDown(data, 0, end)
+	}
+}
+
+// siftDown restores the max-heap order of data[:n] below i.
+func siftDown(data sort.Interface, i, n int) {
+	for {
+		child := 2*i + 1
+		if child >= n {
+			return
+		}
+		if child+1 < n && data.Less(child, child+1) {
+			child++
+		}
+		if !data.Less(i, child) {
+			return
+		}
+		data.Swap(i, child)
+		i = child
+	}
+}
+
+// QuickSort sorts data with a 3-way quicksort.
+func QuickSort(data sort.Interface) {
+	quickSortRange(data, 0, data.Len())
+}
+
+func quickSortRange(data sort.Interface, lo, hi int) {
+	for hi-lo > insertionSortMax {
+		// Move the median of three to lo.
+		mid := lo + (hi-lo)/2
+		if data.Less(mid, lo) {
+			data.Swap(mid, lo)
+		}
+		if data.Less(hi-1, lo) {
+			data.Swap(hi-1, lo)
+		}
+		if data.Less(hi-1, mid) {
+			data.Swap(hi-1, mid)
+		}
+		data.Swap(lo, mid)
+
+		// Dijkstra's partition: data[lo:lt] < pivot, data[lt:i] == pivot,
+		// data[gt:hi] > pivot. data[lt] is always a copy of the pivot.
+		lt, i, gt := lo, lo+1, hi
+		for i < gt {
+			switch {
+			case data.Less(i, lt):
+				data.Swap(lt, i)
+				lt++
+				i++
+			case data.Less(lt, i):
+				// Leave the greater elements already at the end in place.
+				for gt--; gt > i && data.Less(lt, gt); gt-- {
+				}
+				if gt > i {
+					data.Swap(i, gt)
+				}
+			default:
+				i++
+			}
+		}
+		// Recurse into the smaller part and loop on the larger one.
+		if lt-lo < hi-gt {
+			quickSortRange(data, lo, lt)
+			lo = gt
+		} else {
+			quickSortRange(data, gt, hi)
+			hi = lt
+		}
+	}
+	insertionSortRange(data, lo, hi)
+}
+
+// RadixSort sorts data by key with an in-place MSD radix sort.
+func RadixSort(data RadixInterface) {
+	radixSortRange(data, 0, data.Len(), 56)
+}
+
+// radixSortRange sorts data[lo:hi], whose keys agree above bit shift+8, by
+// the byte at shift and then the lower bytes.
+func radixSortRange(data RadixInterface, lo, hi int, shift int) {
+	if hi-lo <= insertionSortMax {
+		insertionSortRange(data, lo, hi)
+		return
+	}
+	digit := func(i int) int { return int(data.Key(i) >> shift & 0xff) }
+	var count, next, end [257]int
+	for i := lo; i < hi; i++ {
+		count[digit(i)]++
+	}
+	next[0] = lo
+	for d := 0; d < 256; d++ {
+		end[d] = next[d] + count[d]
+		next[d+1] = end[d]
+	}
+	// Put each element in its bucket: swap it into the next free slot of
+	// the bucket it belongs to until the current slot holds one that
+	// belongs in the current bucket.
+	for d := 0; d < 256; d++ {
+		for next[d] < end[d] {
+			i := next[d]
+			if e := digit(i); e == d {
+				next[d]++
+			} else {
+				data.Swap(i, next[e])
+				next[e]++
+			}
+		}
+	}
+	if shift == 0 {
+		return
+	}
+	start := lo
+	for d := 0; d < 256; d++ {
+		if end[d]-start > 1 {
+			radixSortRange(data, start, end[d], shift-8)
+		}
+		start = end[d]
+	}
+}
+
+// TimSort sorts data stably with a simplified TimSort.
+func TimSort(data sort.Interface) {
+	n := data.Len()
+	minRun := timMinRun(n)
+	type run struct{ lo, hi int }
+	var runs []run
+	for lo := 0; lo < n; {
+		hi := lo + 1
+		if hi < n {
+			// Find the run starting at lo, reversing it if it is strictly
+			// descending so that reversing keeps the sort stable.
+			if data.Less(hi, lo) {
+				for hi++; hi < n && data.Less(hi, hi-1); hi++ {
+				}
+				reverseRange(data, lo, hi)
+			} else {
+				for hi++; hi < n && !data.Less(hi, hi-1); hi++ {
+				}
+			}
+		}
+		if hi-lo < minRun {
+			end := min(lo+minRun, n)
+			insertionSortRange(data, lo, end)
+			hi = end
+		}
+		runs = append(runs, run{lo, hi})
+		lo = hi
+
+		// Merge runs until their lengths on the stack decrease fast enough
+		// that there are at most log n of them.
+		for len(runs) > 1 {
+			k := len(runs) - 1
+			x, y := runs[k], runs[k-1]
+			if len(runs) > 2 && runs[k-2].hi-runs[k-2].lo <= (y.hi-y.lo)+(x.hi-x.lo) {
+				z := runs[k-2]
+				if z.hi-z.lo < x.hi-x.lo {
+					symMerge(data, z.lo, z.hi, y.hi)
+					runs[k-2], runs[k-1] = run{z.lo, y.hi}, x
+				} else {
+					symMerge(data, y.lo, y.hi, x.hi)
+					runs[k-1] = run{y.lo, x.hi}
+				}
+				runs = runs[:k]
+				continue
+			}
+			if y.hi-y.lo <= x.hi-x.lo {
+				symMerge(data, y.lo, y.hi, x.hi)
+				runs[k-1] = run{y.lo, x.hi}
+				runs = runs[:k]
+				continue
+			}
+			break
+		}
+	}
+	for len(runs) > 1 {
+		k := len(runs) - 1
+		symMerge(data, runs[k-1].lo, runs[k-1].hi, runs[k].hi)
+		runs[k-1].hi = runs[k].hi
+		runs = runs[:k]
+	}
+}
+
+// timMinRun returns the minimum run length for n elements: n itself if it
+// is below 64, and otherwise between 32 and 64, such that n/minRun is a power
+// of two or a little less.
+func timMinRun(n int) int {
+	r := 0 // becomes 1 if any bit is shifted off
+	for n >= 64 {
+		r |= n & 1
+		n >>= 1
+	}
+	return n + r
+}
+
+// A Counting wraps a sort.Interface and counts the calls to Less and Swap.
+// If OnSwap is set, it is called after every swap, for instance to record
+// frames of an animation.
+type Counting struct {
+	sort.Interface
+	Lesses, Swaps int
+	OnSwap        func(i, j int)
+}
+
+func (c *Counting) Less(i, j int) bool {
+	c.Lesses++
+	return c.Interface.Less(i, j)
+}
+
+func (c *Counting) Swap(i, j int) {
+	c.Swaps++
+	c.Interface.Swap(i, j)
+	if c.OnSwap != nil {
+		c.OnSwap(i, j)
+	}
+}
+
+// Key returns the key of element i for RadixSort. It panics if the wrapped
+// Interface is not a RadixInterface.
+func (c *Counting) Key(i int) uint64 {
+	return c.Interface.(RadixInterface).Key(i)
+}
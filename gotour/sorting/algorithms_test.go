@@ -0,0 +1,112 @@
+package sorting
+
+import (
+	"math/rand"
+	"slices"
+	"sort"
+	"testing"
+)
+
+func TestAlgorithmsSort(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	for name, in := range selectionInputs(rng) {
+		want := slices.Sorted(slices.Values(in))
+		for _, a := range vizAlgorithms {
+			d := vizData(slices.Clone(in))
+			a.sort(d)
+			if !slices.Equal(d, want) {
+				t.Errorf("%s on %s: not sorted", a.name, name)
+			}
+		}
+	}
+	for _, kind := range vizInputs {
+		in, err := vizInput(kind, 300, rng)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := slices.Sorted(slices.Values(in))
+		for _, a := range vizAlgorithms {
+			d := slices.Clone(in)
+			a.sort(d)
+			if !slices.Equal(d, want) {
+				t.Errorf("%s on %s input: not sorted", a.name, kind)
+			}
+		}
+	}
+}
+
+// keyed is a slice of elements with a key and their original index, sorted
+// by key only.
+type keyed []struct{ key, index int }
+
+func (k keyed) Len() int           { return len(k) }
+func (k keyed) Less(i, j int) bool { return k[i].key < k[j].key }
+func (k keyed) Swap(i, j int)      { k[i], k[j] = k[j], k[i] }
+
+func TestAlgorithmsStable(t *testing.T) {
+	rng := rand.New(rand.NewSource(2))
+	for _, a := range []struct {
+		name string
+		sort func(sort.Interface)
+	}{
+		{"insertion", InsertionSort},
+		{"merge", MergeSort},
+		{"tim", TimSort},
+	} {
+		for _, n := range []int{0, 1, 10, 100, 1000} {
+			for _, keys := range []int{1, 3, 50} {
+				d := make(keyed, n)
+				for i := range d {
+					d[i].key, d[i].index = rng.Intn(keys), i
+				}
+				a.sort(d)
+				for i := 1; i < n; i++ {
+					if d[i-1].key > d[i].key || d[i-1].key == d[i].key && d[i-1].index > d[i].index {
+						t.Fatalf("%s, n=%d, %d keys: %v before %v", a.name, n, keys, d[i-1], d[i])
+					}
+				}
+			}
+		}
+	}
+}
+
+func TestCounting(t *testing.T) {
+	for _, tt := range []struct {
+		name          string
+		sort          func(sort.Interface)
+		in            vizData
+		lesses, swaps int
+	}{
+		{"insertion sorted", InsertionSort, vizData{1, 2, 3, 4, 5}, 4, 0},
+		{"insertion reversed", InsertionSort, vizData{5, 4, 3, 2, 1}, 10, 10},
+		{"insertion one swap", InsertionSort, vizData{1, 3, 2, 4}, 4, 1},
+		{"merge sorted", MergeSort, vizData{1, 2, 3, 4, 5}, 4, 0},
+		{"tim sorted", TimSort, vizData{1, 2, 3, 4, 5, 6, 7, 8}, 7, 0},
+		{"empty", HeapSort, vizData{}, 0, 0},
+	} {
+		var swapped int
+		c := &Counting{Interface: tt.in, OnSwap: func(i, j int) { swapped++ }}
+		tt.sort(c)
+		if !slices.IsSorted(tt.in) {
+			t.Errorf("%s: not sorted: %v", tt.name, tt.in)
+		}
+		if c.Lesses != tt.lesses || c.Swaps != tt.swaps {
+			t.Errorf("%s: %d comparisons and %d swaps; want %d and %d", tt.name, c.Lesses, c.Swaps, tt.lesses, tt.swaps)
+		}
+		if swapped != c.Swaps {
+			t.Errorf("%s: OnSwap called %d times for %d swaps", tt.name, swapped, c.Swaps)
+		}
+	}
+
+	// Counting passes the keys through to RadixSort, which does not compare
+	// keys that differ in their last byte once the buckets are large enough.
+	d := make(vizData, 256)
+	for i := range d {
+		d[i] = 255 - i
+	}
+	c := &Counting{Interface: d}
+	RadixSort(c)
+	if !slices.IsSorted(d) || c.Lesses != 0 || c.Swaps == 0 {
+		t.Errorf("radix: %v after %d comparisons and %d swaps", d, c.Lesses, c.Swaps)
+	}
+}
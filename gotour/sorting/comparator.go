@@ -0,0 +1,68 @@
+/*
+Comparator chains
+=================
+
+sortingseraching.OrderedBy chains "less" functions to sort by several keys, but
+it only sorts []Change, and it calls each less function up to twice per
+comparison: once to see whether p < q and once more to see whether q < p.
+
+A Comparator is a three-way comparison instead, like cmp.Compare: it returns a
+negative number if a sorts before b, a positive number if a sorts after b and
+0 if they are equal. One call decides between all three cases, so a chain
+calls each comparator at most once per comparison, and moves on to the next
+only when it returns 0.
+
+By builds a chain, Then extends it and Reverse turns it upside down:
+
+	byLines := sorting.Key(func(c Change) int { return c.lines })
+	order := sorting.By(byLanguage).Then(byLines.Reverse(), byUser)
+	slices.SortStableFunc(changes, order)
+
+A Comparator[T] is a func(a, b T) int, so it can be passed straight to
+slices.SortFunc, slices.SortStableFunc, slices.BinarySearchFunc and the
+other functions of the slices package.
+*/
+package sorting
+
+import "cmp"
+
+// A Comparator compares two values: it returns a negative number if a sorts
+// before b, a positive number if a sorts after b and 0 if they are equal.
+type Comparator[T any] func(a, b T) int
+
+// By returns the Comparator that compares with each of cmps in turn, until
+// one of them tells the values apart. With no cmps, all values are equal.
+func By[T any](cmps ...func(a, b T) int) Comparator[T] {
+	switch len(cmps) {
+	case 0:
+		return func(a, b T) int { return 0 }
+	case 1:
+		return cmps[0]
+	}
+	cmps = append([]func(a, b T) int(nil), cmps...)
+	return func(a, b T) int {
+		for _, c := range cmps {
+			if r := c(a, b); r != 0 {
+				return r
+			}
+		}
+		return 0
+	}
+}
+
+// Then returns the Comparator that compares with c, and with next in turn
+// for the values c finds equal.
+func (c Comparator[T]) Then(next ...func(a, b T) int) Comparator[T] {
+	return By(append([]func(a, b T) int{c}, next...)...)
+}
+
+// Reverse returns the Comparator that sorts in the opposite order to c.
+func (c Comparator[T]) Reverse() Comparator[T] {
+	return func(a, b T) int { return c(b, a) }
+}
+
+// Key returns the Comparator that orders values by the key that key
+// extracts from them.
+func Key[T any, K cmp.Ordered](key func(T) K) Comparator[T] {
+	return func(a, b T) int { return cmp.Compare(key(a), key(b)) }
+}
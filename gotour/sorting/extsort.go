@@ -0,0 +1,379 @@
+package sorting
+
+/*
+External sorting
+================
+
+slices.Sort and sort.Sort need the whole data set in memory. An external sort
+handles data sets larger than memory in two phases:
+
+ 1. Read records until the memory budget is used up, sort this chunk in
+    memory and write it to a temporary file. Repeat until the input ends.
+ 2. Merge the sorted chunks. A heap holds the next record of every chunk; the
+    smallest is written to the output and replaced by the next record of its
+    chunk. Only one record per chunk is in memory at any time.
+
+If there are more chunks than can be merged at once (mergeFanIn), groups of
+them are merged into bigger chunks first. If the whole input fits in the
+budget, it is sorted in memory and no temporary file is written.
+
+Records and keys
+================
+
+A record is a line of text. It is sorted by a key taken from it:
+
+    LineKey            the whole line
+    CSVKey(n, comma)   the nth field of the line read as a CSV record, or
+                       empty if it has fewer fields
+    FixedWidthKey      the bytes from start to end, for fixed-width columns
+
+Keys are compared as strings, as numbers with Numeric (keys that are not
+numbers then sort before all numbers), or by any Comparator of strings. The sort is stable: records with equal
+keys keep their input order, because chunks are sorted stably and the merge
+prefers the earlier chunk when keys are equal.
+*/
+
+import (
+	"bufio"
+	"cmp"
+	"container/heap"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+const (
+	mergeFanIn     = 64       // most chunks merged at once
+	recordOverhead = 64       // bytes of memory per record besides its text
+	defaultBudget  = 64 << 20 // bytes, if ExternalSort.MemoryBudget is 0
+)
+
+// A KeyFunc returns the sort key of a line.
+type KeyFunc func(line string) (string, error)
+
+// LineKey is the KeyFunc of the whole line.
+func LineKey(line string) (string, error) {
+	return line, nil
+}
+
+// CSVKey returns the KeyFunc of field n (counting from 0) of the line read
+// as a CSV record with the given field separator. Fields may be quoted but
+// may not contain newlines. A record with fewer fields has an empty key, as
+// with sort -k.
+func CSVKey(n int, comma rune) KeyFunc {
+	return func(line string) (string, error) {
+		r := csv.NewReader(strings.NewReader(line))
+		r.Comma = comma
+		r.FieldsPerRecord = -1
+		fields, err := r.Read()
+		if err == io.EOF {
+			fields, err = nil, nil // an empty line
+		}
+		if err != nil {
+			return "", err
+		}
+		if n >= len(fields) {
+			return "", nil
+		}
+		return fields[n], nil
+	}
+}
+
+// FixedWidthKey returns the KeyFunc of the bytes from start up to end of the
+// line, or as many of them as the line has. An end of 0 or less means the
+// end of the line.
+func FixedWidthKey(start, end int) KeyFunc {
+	return func(line string) (string, error) {
+		if end > 0 && end < len(line) {
+			line = line[:end]
+		}
+		if start >= len(line) {
+			return "", nil
+		}
+		return line[start:], nil
+	}
+}
+
+// An ExternalSort sorts lines of text that need not fit in memory.
+type ExternalSort struct {
+	Key          KeyFunc            // the sort key of a line; LineKey if nil
+	Numeric      bool               // compare keys as numbers
+	Compare      Comparator[string] // compares keys, instead of Numeric, if not nil
+	Reverse      bool               // sort in decreasing order
+	MemoryBudget int64              // bytes of records to sort in memory; 64 MiB if 0
+	TempDir      string             // directory for temporary files; os.TempDir() if ""
+}
+
+// A record is a line with its key.
+type record struct {
+	line  string
+	key   string
+	num   float64
+	isNum bool
+}
+
+// newRecord returns the record of line.
+func (s *ExternalSort) newRecord(line string) (record, error) {
+	key, err := LineKey(line)
+	if s.Key != nil {
+		key, err = s.Key(line)
+	}
+	r := record{line: line, key: key}
+	if s.Numeric {
+		num, err := strconv.ParseFloat(strings.TrimSpace(key), 64)
+		r.num, r.isNum = num, err == nil
+	}
+	return r, err
+}
+
+// compare compares the keys of a and b.
+func (s *ExternalSort) compare(a, b record) int {
+	var c int
+	switch {
+	case s.Compare != nil:
+		c = s.Compare(a.key, b.key)
+	case !s.Numeric:
+		c = strings.Compare(a.key, b.key)
+	case a.isNum && b.isNum:
+		c = cmp.Compare(a.num, b.num)
+	case a.isNum != b.isNum:
+		c = -1
+		if a.isNum {
+			c = 1
+		}
+	default:
+		c = strings.Compare(a.key, b.key)
+	}
+	if s.Reverse {
+		return -c
+	}
+	return c
+}
+
+// lineReader reads lines, without their line endings.
+type lineReader struct {
+	r    *bufio.Reader
+	line int // number of the last line read, for errors
+}
+
+func newLineReader(r io.Reader) *lineReader {
+	return &lineReader{r: bufio.NewReaderSize(r, 64<<10)}
+}
+
+// next returns the next line, or io.EOF.
+func (lr *lineReader) next() (string, error) {
+	line, err := lr.r.ReadString('\n')
+	if err == io.EOF && line != "" {
+		err = nil
+	}
+	if err != nil {
+		return "", err
+	}
+	lr.line++
+	return strings.TrimSuffix(line, "\n"), nil
+}
+
+// Sort reads the lines of the readers in turn and writes them to w in order.
+func (s *ExternalSort) Sort(w io.Writer, readers ...io.Reader) (err error) {
+	budget := s.MemoryBudget
+	if budget <= 0 {
+		budget = defaultBudget
+	}
+	dir, err := os.MkdirTemp(s.TempDir, "extsort")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dir)
+
+	var chunks []string
+	var records []record
+	var size int64
+	spill := func() error {
+		slices.SortStableFunc(records, s.compare)
+		name, err := s.writeChunk(dir, records)
+		chunks = append(chunks, name)
+		records, size = records[:0], 0
+		return err
+	}
+	for i, r := range readers {
+		lr := newLineReader(r)
+		for {
+			line, err := lr.next()
+			if err == io.EOF {
+				break
+			} else if err != nil {
+				return err
+			}
+			rec, err := s.newRecord(line)
+			if err != nil {
+				return fmt.Errorf("input %d, line %d: %w", i+1, lr.line, err)
+			}
+			records = append(records, rec)
+			size += int64(len(line)+len(rec.key)) + recordOverhead
+			if size >= budget {
+				if err := spill(); err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	bw := bufio.NewWriter(w)
+	if len(chunks) == 0 {
+		// Everything fits in memory.
+		slices.SortStableFunc(records, s.compare)
+		for _, r := range records {
+			bw.WriteString(r.line)
+			bw.WriteByte('\n')
+		}
+		return bw.Flush()
+	}
+	if len(records) > 0 {
+		if err := spill(); err != nil {
+			return err
+		}
+	}
+	for len(chunks) > mergeFanIn {
+		var merged []string
+		for start := 0; start < len(chunks); start += mergeFanIn {
+			group := chunks[start:min(start+mergeFanIn, len(chunks))]
+			name, err := s.mergeToChunk(dir, group)
+			if err != nil {
+				return err
+			}
+			merged = append(merged, name)
+		}
+		chunks = merged
+	}
+	if err := s.merge(bw, chunks); err != nil {
+		return err
+	}
+	return bw.Flush()
+}
+
+// writeChunk writes the lines of records to a new temporary file in dir and
+// returns its name.
+func (s *ExternalSort) writeChunk(dir string, records []record) (string, error) {
+	f, err := os.CreateTemp(dir, "chunk")
+	if err != nil {
+		return "", err
+	}
+	bw := bufio.NewWriter(f)
+	for _, r := range records {
+		bw.WriteString(r.line)
+		bw.WriteByte('\n')
+	}
+	err = bw.Flush()
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	return f.Name(), err
+}
+
+// mergeToChunk merges the chunks into a new temporary file in dir, removes
+// them and returns the name of the new file.
+func (s *ExternalSort) mergeToChunk(dir string, chunks []string) (string, error) {
+	f, err := os.CreateTemp(dir, "chunk")
+	if err != nil {
+		return "", err
+	}
+	bw := bufio.NewWriter(f)
+	err = s.merge(bw, chunks)
+	if err == nil {
+		err = bw.Flush()
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	for _, c := range chunks {
+		os.Remove(c)
+	}
+	return f.Name(), err
+}
+
+// merge writes the lines of the sorted chunks to w in order.
+func (s *ExternalSort) merge(w *bufio.Writer, chunks []string) error {
+	h := &mergeHeap{s: s}
+	for i, name := range chunks {
+		f, err := os.Open(name)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		src := &mergeSource{lr: newLineReader(f), index: i}
+		if err := h.advance(src); err != nil {
+			return err
+		}
+		if !src.done {
+			h.sources = append(h.sources, src)
+		}
+	}
+	heap.Init(h)
+	for h.Len() > 0 {
+		src := h.sources[0]
+		w.WriteString(src.rec.line)
+		w.WriteByte('\n')
+		if err := h.advance(src); err != nil {
+			return err
+		}
+		if src.done {
+			heap.Pop(h)
+		} else {
+			heap.Fix(h, 0)
+		}
+	}
+	return nil
+}
+
+// A mergeSource is a chunk being merged, with its next record.
+type mergeSource struct {
+	lr    *lineReader
+	index int // of the chunk, to keep the merge stable
+	rec   record
+	done  bool
+}
+
+// A mergeHeap is a heap of the sources with records left, the source with
+// the smallest record first.
+type mergeHeap struct {
+	s       *ExternalSort
+	sources []*mergeSource
+}
+
+// advance reads the next record of src.
+func (h *mergeHeap) advance(src *mergeSource) error {
+	line, err := src.lr.next()
+	if err == io.EOF {
+		src.done = true
+		return nil
+	} else if err != nil {
+		return err
+	}
+	src.rec, err = h.s.newRecord(line)
+	return err
+}
+
+func (h *mergeHeap) Len() int { return len(h.sources) }
+
+func (h *mergeHeap) Less(i, j int) bool {
+	a, b := h.sources[i], h.sources[j]
+	if c := h.s.compare(a.rec, b.rec); c != 0 {
+		return c < 0
+	}
+	return a.index < b.index
+}
+
+func (h *mergeHeap) Swap(i, j int) { h.sources[i], h.sources[j] = h.sources[j], h.sources[i] }
+
+func (h *mergeHeap) Push(x any) { h.sources = append(h.sources, x.(*mergeSource)) }
+
+func (h *mergeHeap) Pop() any {
+	n := len(h.sources) - 1
+	src := h.sources[n]
+	h.sources = h.sources[:n]
+	return src
+}
@@ -0,0 +1,138 @@
+package sorting
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+const extsortUsage = `usage: extsort [-csv field [-comma c] | -cols start-end] [-n] [-r] [-mem size] [-T dir] [-o file] [file ...]`
+
+// ExtSortCommand runs the extsort command with the given arguments. It sorts
+// the lines of the files, or of stdin if there are none, with an
+// ExternalSort and writes them to stdout or to the -o file. Lines are sorted
+// by the whole line, by a CSV field with -csv (counting from 1) or by the
+// byte columns of -cols, such as "10-19" (counting from 1, both included).
+// The -mem budget is a number of bytes with an optional K, M or G suffix.
+func ExtSortCommand(args []string, stdin io.Reader, stdout io.Writer) error {
+	fs := flag.NewFlagSet("extsort", flag.ContinueOnError)
+	field := fs.Int("csv", 0, "sort by this CSV field, counting from 1")
+	comma := fs.String("comma", ",", "CSV field separator")
+	cols := fs.String("cols", "", "sort by these byte columns, such as 10-19")
+	var s ExternalSort
+	fs.BoolVar(&s.Numeric, "n", false, "compare keys as numbers")
+	fs.BoolVar(&s.Reverse, "r", false, "sort in decreasing order")
+	mem := fs.String("mem", "64M", "memory budget for sorting in memory")
+	fs.StringVar(&s.TempDir, "T", "", "directory for temporary files")
+	output := fs.String("o", "", "file to write the sorted lines to")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	switch {
+	case *field > 0 && *cols != "":
+		return errors.New("extsort: -csv and -cols cannot be used together\n" + extsortUsage)
+	case *field < 0:
+		return fmt.Errorf("extsort: invalid field %d", *field)
+	case *field > 0:
+		c, size := utf8.DecodeRuneInString(*comma)
+		if size == 0 || size != len(*comma) {
+			return fmt.Errorf("extsort: invalid separator %q", *comma)
+		}
+		s.Key = CSVKey(*field-1, c)
+	case *cols != "":
+		start, end, err := parseColumns(*cols)
+		if err != nil {
+			return fmt.Errorf("extsort: %w", err)
+		}
+		s.Key = FixedWidthKey(start, end)
+	}
+	budget, err := parseSize(*mem)
+	if err != nil {
+		return fmt.Errorf("extsort: %w", err)
+	}
+	s.MemoryBudget = budget
+
+	var inputs []io.Reader
+	for _, name := range fs.Args() {
+		f, err := os.Open(name)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		inputs = append(inputs, f)
+	}
+	if len(inputs) == 0 {
+		inputs = append(inputs, stdin)
+	}
+	if *output == "" {
+		return s.Sort(stdout, inputs...)
+	}
+	// Write to a new file and rename it, so that the output can be one of
+	// the inputs. CreateTemp makes the file private to its owner, so give it
+	// the mode of the file it replaces, or the usual mode of a new file.
+	mode := os.FileMode(0644)
+	if info, err := os.Stat(*output); err == nil {
+		mode = info.Mode().Perm()
+	}
+	f, err := os.CreateTemp(filepath.Dir(*output), ".extsort")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+	if err := f.Chmod(mode); err != nil {
+		f.Close()
+		return err
+	}
+	err = s.Sort(f, inputs...)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), *output)
+}
+
+// parseColumns parses byte columns such as "10-19" or "10-", counting from
+// 1, into the start and end of FixedWidthKey.
+func parseColumns(cols string) (start, end int, err error) {
+	from, to, _ := strings.Cut(cols, "-")
+	if start, err = strconv.Atoi(from); err != nil || start < 1 {
+		return 0, 0, fmt.Errorf("invalid columns %q", cols)
+	}
+	if to != "" {
+		if end, err = strconv.Atoi(to); err != nil || end < start {
+			return 0, 0, fmt.Errorf("invalid columns %q", cols)
+		}
+	}
+	return start - 1, end, nil
+}
+
+// parseSize parses a number of bytes with an optional K, M or G suffix.
+func parseSize(size string) (int64, error) {
+	shift := 0
+	switch strings.ToUpper(size[len(size)-min(len(size), 1):]) {
+	case "K":
+		shift = 10
+	case "M":
+		shift = 20
+	case "G":
+		shift = 30
+	}
+	digits := size
+	if shift > 0 {
+		digits = size[:len(size)-1]
+	}
+	n, err := strconv.ParseInt(digits, 10, 64)
+	if err != nil || n <= 0 || n > 1<<(62-shift) {
+		return 0, fmt.Errorf("invalid size %q", size)
+	}
+	return n << shift, nil
+}
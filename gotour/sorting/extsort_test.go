@@ -0,0 +1,108 @@
+package sorting
+
+import (
+	"fmt"
+	"io"
+	"math/rand"
+	"os"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// extsortLines returns n lines "key,index" with few distinct keys, so that
+// stability matters.
+func extsortLines(n int, rng *rand.Rand) []string {
+	lines := make([]string, n)
+	for i := range lines {
+		lines[i] = fmt.Sprintf("k%02d,%d", rng.Intn(50), i)
+	}
+	return lines
+}
+
+// sortLines runs s over the lines, split between the given number of
+// readers, and returns the output lines.
+func sortLines(t *testing.T, s *ExternalSort, lines []string, readers int) []string {
+	t.Helper()
+	var inputs []io.Reader
+	for k := range readers {
+		part := lines[k*len(lines)/readers : (k+1)*len(lines)/readers]
+		text := strings.Join(part, "\n")
+		if len(part) > 0 {
+			text += "\n"
+		}
+		inputs = append(inputs, strings.NewReader(text))
+	}
+	var out strings.Builder
+	if err := s.Sort(&out, inputs...); err != nil {
+		t.Fatal(err)
+	}
+	if out.Len() == 0 {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(out.String(), "\n"), "\n")
+}
+
+func TestExternalSortSpills(t *testing.T) {
+	lines := extsortLines(3000, rand.New(rand.NewSource(1)))
+	want := slices.Clone(lines)
+	slices.SortStableFunc(want, func(a, b string) int { return strings.Compare(a[:3], b[:3]) })
+
+	for _, budget := range []int64{1, 2000, 50000, 0} {
+		dir := t.TempDir()
+		s := &ExternalSort{Key: CSVKey(0, ','), MemoryBudget: budget, TempDir: dir}
+		// A budget of 1 byte spills every record, more chunks than
+		// mergeFanIn, so that groups of chunks are merged first.
+		got := sortLines(t, s, lines, 3)
+		if !slices.Equal(got, want) {
+			t.Errorf("budget %d: output is not the stable sort of the input", budget)
+		}
+		if left, _ := os.ReadDir(dir); len(left) != 0 {
+			t.Errorf("budget %d: %d temporary files left", budget, len(left))
+		}
+	}
+}
+
+func TestExternalSortEmpty(t *testing.T) {
+	for _, budget := range []int64{1, 0} {
+		s := &ExternalSort{MemoryBudget: budget, TempDir: t.TempDir()}
+		if got := sortLines(t, s, nil, 1); len(got) != 0 {
+			t.Errorf("budget %d: empty input sorted to %q", budget, got)
+		}
+		var out strings.Builder
+		if err := s.Sort(&out); err != nil || out.Len() != 0 {
+			t.Errorf("budget %d: no readers sorted to %q, %v", budget, out.String(), err)
+		}
+	}
+}
+
+func TestExternalSortCompare(t *testing.T) {
+	lines := []string{"banana", "Apple", "cherry", "apple", "Banana", "APPLE"}
+	for _, budget := range []int64{1, 0} {
+		s := &ExternalSort{
+			Compare:      func(a, b string) int { return strings.Compare(strings.ToLower(a), strings.ToLower(b)) },
+			MemoryBudget: budget,
+			TempDir:      t.TempDir(),
+		}
+		want := []string{"Apple", "apple", "APPLE", "banana", "Banana", "cherry"}
+		if got := sortLines(t, s, lines, 2); !slices.Equal(got, want) {
+			t.Errorf("budget %d: case-insensitive sort = %q; want %q", budget, got, want)
+		}
+
+		s.Reverse = true
+		want = []string{"cherry", "banana", "Banana", "Apple", "apple", "APPLE"}
+		if got := sortLines(t, s, lines, 2); !slices.Equal(got, want) {
+			t.Errorf("budget %d: reversed case-insensitive sort = %q; want %q", budget, got, want)
+		}
+	}
+}
+
+func TestExternalSortNumeric(t *testing.T) {
+	lines := []string{"10 b", "9 a", "x", "-1.5 c", "", "10 a"}
+	s := &ExternalSort{Key: CSVKey(0, ' '), Numeric: true, MemoryBudget: 1, TempDir: t.TempDir()}
+	// Keys that are not numbers sort first, as strings.
+	want := []string{"", "x", "-1.5 c", "9 a", "10 b", "10 a"}
+	if got := sortLines(t, s, lines, 1); !slices.Equal(got, want) {
+		t.Errorf("numeric sort = %q; want %q", got, want)
+	}
+}
@@ -0,0 +1,260 @@
+package sorting
+
+/*
+Selection
+=========
+
+Sorting puts every element in its place. Often only some places matter: the
+median, the 10 largest values, the first page of results. These can be found
+without sorting everything.
+
+NthElement rearranges a slice so that the element at index n is the one that
+would be there if the slice were sorted, with no larger element before it and
+no smaller element after it. It uses quickselect: partition the slice around
+a pivot as quicksort does, but continue only with the part containing n. On
+average this takes time proportional to the length of the slice.
+
+Worst-case guarantees
+=====================
+
+A bad pivot, such as the smallest element, shrinks the part containing n by
+only one element, and a sequence of bad pivots takes quadratic time. The
+median of medians pivot avoids this: split the elements into groups of five,
+take the median of each group and then, recursively, the median of those
+medians. At least 30% of the elements are smaller than this pivot and at
+least 30% are larger, so every partition shrinks the part by 30%, and the
+selection takes linear time in the worst case.
+
+The median of medians costs more to find than a median of three, so
+NthElement starts with the median of three, and switches to the median of
+medians as soon as a partition fails to shrink the part by a quarter. Either
+way the time is linear.
+
+Partial sorts and streams
+=========================
+
+PartialSort sorts the k smallest elements into the start of a slice: select
+the kth, then sort the k before it.
+
+TopK keeps the k largest of a stream of values, without storing the stream.
+It holds them in a heap with the smallest of them at the root: a new value is
+only kept if it is larger than the root, which it replaces.
+
+Each function comes in two forms, as in the slices package: one for
+cmp.Ordered elements and one, ending in Func, taking a comparison function
+such as a Comparator.
+*/
+
+import (
+	"cmp"
+	"iter"
+	"slices"
+)
+
+// insertionSortMax is the length of the part below which selection sorts
+// it with an insertion sort.
+const insertionSortMax = 12
+
+// NthElement rearranges s so that s[n] is the element that would be there if
+// s were sorted, the elements before it are less than or equal to it and the
+// elements after it are greater than or equal to it. It panics if n is out
+// of range.
+func NthElement[S ~[]E, E cmp.Ordered](s S, n int) {
+	NthElementFunc(s, n, cmp.Compare[E])
+}
+
+// NthElementFunc is like NthElement but orders the elements by cmp.
+func NthElementFunc[S ~[]E, E any](s S, n int, cmp func(a, b E) int) {
+	if n < 0 || n >= len(s) {
+		panic("sorting: NthElement index out of range")
+	}
+	selectNth(s, 0, len(s), n, false, cmp)
+}
+
+// selectNth selects the nth element within s[lo:hi], using median of
+// medians pivots from the start if mom is set.
+func selectNth[E any](s []E, lo, hi, n int, mom bool, cmp func(a, b E) int) {
+	for hi-lo > insertionSortMax {
+		var p E
+		if mom {
+			p = medianOfMedians(s, lo, hi, cmp)
+		} else {
+			p = medianOfThree(s[lo], s[lo+(hi-lo)/2], s[hi-1], cmp)
+		}
+		lt, gt := partition3(s, lo, hi, p, cmp)
+		size := hi - lo
+		switch {
+		case n < lt:
+			hi = lt
+		case n >= gt:
+			lo = gt
+		default:
+			return // s[lt:gt] all equal the pivot
+		}
+		if 4*(hi-lo) > 3*size {
+			mom = true
+		}
+	}
+	insertionSort(s[lo:hi], cmp)
+}
+
+// partition3 rearranges s[lo:hi] into the elements less than p, those equal
+// to p and those greater than p, and returns the bounds [lt, gt) of the
+// equal ones.
+func partition3[E any](s []E, lo, hi int, p E, cmp func(a, b E) int) (lt, gt int) {
+	lt, gt = lo, hi
+	for i := lo; i < gt; {
+		switch c := cmp(s[i], p); {
+		case c < 0:
+			s[lt], s[i] = s[i], s[lt]
+			lt++
+			i++
+		case c > 0:
+			gt--
+			s[i], s[gt] = s[gt], s[i]
+		default:
+			i++
+		}
+	}
+	return lt, gt
+}
+
+// medianOfThree returns the median of a, b and c.
+func medianOfThree[E any](a, b, c E, cmp func(a, b E) int) E {
+	if cmp(a, b) > 0 {
+		a, b = b, a
+	}
+	if cmp(b, c) > 0 {
+		b = c
+		if cmp(a, b) > 0 {
+			b = a
+		}
+	}
+	return b
+}
+
+// medianOfMedians returns the median of the medians of the groups of five
+// elements of s[lo:hi]. It moves the medians to the start of s[lo:hi].
+func medianOfMedians[E any](s []E, lo, hi int, cmp func(a, b E) int) E {
+	m := lo
+	for g := lo; g < hi; g += 5 {
+		end := min(g+5, hi)
+		insertionSort(s[g:end], cmp)
+		median := g + (end-g)/2
+		s[m], s[median] = s[median], s[m]
+		m++
+	}
+	mid := lo + (m-lo)/2
+	selectNth(s, lo, m, mid, true, cmp)
+	return s[mid]
+}
+
+func insertionSort[E any](s []E, cmp func(a, b E) int) {
+	for i := 1; i < len(s); i++ {
+		for j := i; j > 0 && cmp(s[j], s[j-1]) < 0; j-- {
+			s[j], s[j-1] = s[j-1], s[j]
+		}
+	}
+}
+
+// PartialSort rearranges s so that s[:k] holds its k smallest elements in
+// order. The order of the other elements is unspecified. If k is greater
+// than len(s), all of s is sorted.
+func PartialSort[S ~[]E, E cmp.Ordered](s S, k int) {
+	PartialSortFunc(s, k, cmp.Compare[E])
+}
+
+// PartialSortFunc is like PartialSort but orders the elements by cmp.
+func PartialSortFunc[S ~[]E, E any](s S, k int, cmp func(a, b E) int) {
+	if k <= 0 {
+		return
+	}
+	if k < len(s) {
+		NthElementFunc(s, k-1, cmp)
+		s = s[:k]
+	}
+	slices.SortFunc(s, cmp)
+}
+
+// A TopK keeps the k largest of the values pushed to it.
+type TopK[E any] struct {
+	k    int
+	cmp  func(a, b E) int
+	heap []E // a min-heap: heap[0] is the smallest value kept
+}
+
+// NewTopK returns a TopK keeping the k largest values.
+func NewTopK[E cmp.Ordered](k int) *TopK[E] {
+	return NewTopKFunc(k, cmp.Compare[E])
+}
+
+// NewTopKFunc returns a TopK keeping the k largest values as ordered by cmp.
+func NewTopKFunc[E any](k int, cmp func(a, b E) int) *TopK[E] {
+	return &TopK[E]{k: k, cmp: cmp, heap: make([]E, 0, max(k, 0))}
+}
+
+// Push offers v to t, which keeps it if it is among the k largest values
+// pushed so far.
+func (t *TopK[E]) Push(v E) {
+	switch {
+	case len(t.heap) < t.k:
+		t.heap = append(t.heap, v)
+		t.up(len(t.heap) - 1)
+	case len(t.heap) > 0 && t.cmp(v, t.heap[0]) > 0:
+		t.heap[0] = v
+		t.down(0)
+	}
+}
+
+// Len returns the number of values kept, at most k.
+func (t *TopK[E]) Len() int {
+	return len(t.heap)
+}
+
+// Sorted returns the values kept, largest first.
+func (t *TopK[E]) Sorted() []E {
+	s := slices.Clone(t.heap)
+	slices.SortFunc(s, func(a, b E) int { return t.cmp(b, a) })
+	return s
+}
+
+func (t *TopK[E]) up(i int) {
+	for i > 0 {
+		parent := (i - 1) / 2
+		if t.cmp(t.heap[i], t.heap[parent]) >= 0 {
+			return
+		}
+		t.heap[i], t.heap[parent] = t.heap[parent], t.heap[i]
+		i = parent
+	}
+}
+
+func (t *TopK[E]) down(i int) {
+	for {
+		smallest := i
+		for _, c := range []int{2*i + 1, 2*i + 2} {
+			if c < len(t.heap) && t.cmp(t.heap[c], t.heap[smallest]) < 0 {
+				smallest = c
+			}
+		}
+		if smallest == i {
+			return
+		}
+		t.heap[i], t.heap[smallest] = t.heap[smallest], t.heap[i]
+		i = smallest
+	}
+}
+
+// TopKOf returns the k largest values of seq, largest first.
+func TopKOf[E cmp.Ordered](seq iter.Seq[E], k int) []E {
+	return TopKOfFunc(seq, k, cmp.Compare[E])
+}
+
+// TopKOfFunc is like TopKOf but orders the values by cmp.
+func TopKOfFunc[E any](seq iter.Seq[E], k int, cmp func(a, b E) int) []E {
+	t := NewTopKFunc(k, cmp)
+	for v := range seq {
+		t.Push(v)
+	}
+	return t.Sorted()
+}
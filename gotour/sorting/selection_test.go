@@ -0,0 +1,99 @@
+package sorting
+
+import (
+	"cmp"
+	"math/rand"
+	"slices"
+	"strconv"
+	"testing"
+)
+
+// selectionInputs returns inputs of several lengths and shapes: random,
+// with many duplicates, sorted, reversed and organ-pipe.
+func selectionInputs(rng *rand.Rand) map[string][]int {
+	inputs := make(map[string][]int)
+	for _, n := range []int{0, 1, 2, 5, 13, 64, 257, 1000} {
+		random, dups, sorted, pipe := make([]int, n), make([]int, n), make([]int, n), make([]int, n)
+		for i := range n {
+			random[i] = rng.Intn(1 << 20)
+			dups[i] = rng.Intn(4)
+			sorted[i] = i
+			pipe[i] = min(i, n-1-i)
+		}
+		reversed := slices.Clone(sorted)
+		slices.Reverse(reversed)
+		for name, s := range map[string][]int{"random": random, "dups": dups, "sorted": sorted, "reversed": reversed, "pipe": pipe} {
+			inputs[name+"/"+strconv.Itoa(n)] = s
+		}
+	}
+	return inputs
+}
+
+// checkNth checks that s is a permutation of sorted with s[n] in its sorted
+// place and no larger element before it or smaller element after it.
+func checkNth(t *testing.T, name string, s, sorted []int, n int) {
+	t.Helper()
+	if s[n] != sorted[n] {
+		t.Fatalf("%s: s[%d] = %d, want %d", name, n, s[n], sorted[n])
+	}
+	for i, v := range s {
+		if i < n && v > s[n] || i > n && v < s[n] {
+			t.Fatalf("%s: s[%d] = %d is on the wrong side of s[%d] = %d", name, i, v, n, s[n])
+		}
+	}
+	if got := slices.Sorted(slices.Values(s)); !slices.Equal(got, sorted) {
+		t.Fatalf("%s: the elements changed", name)
+	}
+}
+
+func TestNthElement(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	for name, in := range selectionInputs(rng) {
+		sorted := slices.Sorted(slices.Values(in))
+		for _, n := range []int{0, len(in) / 3, len(in) / 2, len(in) - 1} {
+			if len(in) == 0 {
+				break
+			}
+			s := slices.Clone(in)
+			NthElement(s, n)
+			checkNth(t, name, s, sorted, n)
+
+			// The same with median of medians pivots from the start.
+			s = slices.Clone(in)
+			selectNth(s, 0, len(s), n, true, cmp.Compare[int])
+			checkNth(t, name+"/mom", s, sorted, n)
+		}
+	}
+}
+
+func TestPartialSort(t *testing.T) {
+	rng := rand.New(rand.NewSource(2))
+	for name, in := range selectionInputs(rng) {
+		sorted := slices.Sorted(slices.Values(in))
+		for _, k := range []int{0, 1, len(in) / 2, len(in), len(in) + 1} {
+			s := slices.Clone(in)
+			PartialSort(s, k)
+			k = min(k, len(s))
+			if !slices.Equal(s[:k], sorted[:k]) {
+				t.Fatalf("%s: PartialSort(%d) = %v, want %v", name, k, s[:k], sorted[:k])
+			}
+			if got := slices.Sorted(slices.Values(s)); !slices.Equal(got, sorted) {
+				t.Fatalf("%s: PartialSort(%d) changed the elements", name, k)
+			}
+		}
+	}
+}
+
+func TestTopK(t *testing.T) {
+	rng := rand.New(rand.NewSource(3))
+	for name, in := range selectionInputs(rng) {
+		desc := slices.Sorted(slices.Values(in))
+		slices.Reverse(desc)
+		for _, k := range []int{0, 1, 10, len(in), len(in) + 1} {
+			got := TopKOf(slices.Values(in), k)
+			if want := desc[:min(k, len(desc))]; !slices.Equal(got, want) {
+				t.Fatalf("%s: TopKOf(%d) = %v, want %v", name, k, got, want)
+			}
+		}
+	}
+}
@@ -0,0 +1,256 @@
+package sorting
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"image"
+	"image/color"
+	"image/gif"
+	"io"
+	"math/rand"
+	"os"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+)
+
+const sortvizUsage = `usage:
+	sortviz table [-n n] [-seed n]
+	sortviz frames [-algo name] [-input kind] [-n n] [-every k] [-seed n]
+	sortviz gif [-algo name] [-input kind] [-n n] [-every k] [-delay d] [-seed n] -o file`
+
+// maxFrames is the number of frames to aim for when -every is 0.
+const maxFrames = 200
+
+// vizData is the data sorted by sortviz: a permutation of 0..n-1 or a few
+// distinct values.
+type vizData []int
+
+func (d vizData) Len() int           { return len(d) }
+func (d vizData) Less(i, j int) bool { return d[i] < d[j] }
+func (d vizData) Swap(i, j int)      { d[i], d[j] = d[j], d[i] }
+func (d vizData) Key(i int) uint64   { return uint64(d[i]) }
+
+// A vizAlgorithm is a sorting algorithm that sortviz can run.
+type vizAlgorithm struct {
+	name string
+	sort func(RadixInterface)
+}
+
+// vizAlgorithms are the algorithms compared by sortviz, in order.
+var vizAlgorithms = []vizAlgorithm{
+	{"insertion", func(d RadixInterface) { InsertionSort(d) }},
+	{"merge", func(d RadixInterface) { MergeSort(d) }},
+	{"heap", func(d RadixInterface) { HeapSort(d) }},
+	{"quick", func(d RadixInterface) { QuickSort(d) }},
+	{"radix", RadixSort},
+	{"tim", func(d RadixInterface) { TimSort(d) }},
+	{"sort.Sort", func(d RadixInterface) { sort.Sort(d) }},
+	{"sort.Stable", func(d RadixInterface) { sort.Stable(d) }},
+}
+
+// vizInputs are the kinds of input sortviz can generate.
+var vizInputs = []string{"random", "sorted", "reversed", "nearly", "few"}
+
+// vizInput returns n elements of the kind of input.
+func vizInput(kind string, n int, rng *rand.Rand) (vizData, error) {
+	d := make(vizData, n)
+	for i := range d {
+		d[i] = i
+	}
+	switch kind {
+	case "random":
+		rng.Shuffle(n, d.Swap)
+	case "sorted":
+	case "reversed":
+		slices.Reverse(d)
+	case "nearly":
+		for k := 0; k < n/20; k++ {
+			d.Swap(rng.Intn(n), rng.Intn(n))
+		}
+	case "few":
+		for i := range d {
+			d[i] = rng.Intn(5) * (n - 1) / 4
+		}
+	default:
+		return nil, fmt.Errorf("unknown input %q, want one of %s", kind, strings.Join(vizInputs, ", "))
+	}
+	return d, nil
+}
+
+// SortVizCommand runs the sortviz command with the given arguments:
+//
+//	sortviz table [-n n] [-seed n]
+//
+// sorts every kind of input with every algorithm and prints the number of
+// calls to Less and Swap and the time taken.
+//
+//	sortviz frames [-algo name] [-input kind] [-n n] [-every k] [-seed n]
+//
+// prints the data as a line of bars after every k swaps.
+//
+//	sortviz gif [-algo name] [-input kind] [-n n] [-every k] [-delay d] [-seed n] -o file
+//
+// writes the same frames as an animated GIF, with d hundredths of a second
+// between frames. With -every 0, k is chosen to give about maxFrames frames.
+func SortVizCommand(args []string, stdout io.Writer) error {
+	if len(args) == 0 {
+		return errors.New(sortvizUsage)
+	}
+	fs := flag.NewFlagSet("sortviz "+args[0], flag.ContinueOnError)
+	n := fs.Int("n", 0, "number of elements (default 1000 for table, 40 for frames, 100 for gif)")
+	seed := fs.Int64("seed", 1, "seed for generating the input")
+	algo := fs.String("algo", "quick", "algorithm to animate")
+	input := fs.String("input", "random", "kind of input: "+strings.Join(vizInputs, ", "))
+	every := fs.Int("every", 0, "swaps between frames (0 to choose)")
+	delay := fs.Int("delay", 4, "delay between GIF frames in 1/100 s")
+	output := fs.String("o", "", "GIF file to write")
+	if err := fs.Parse(args[1:]); err != nil {
+		return err
+	}
+	defaults := map[string]int{"table": 1000, "frames": 40, "gif": 100}
+	if _, ok := defaults[args[0]]; !ok {
+		return fmt.Errorf("sortviz: unknown command %q\n%s", args[0], sortvizUsage)
+	}
+	if *n == 0 {
+		*n = defaults[args[0]]
+	}
+	if *n < 1 || *every < 0 {
+		return errors.New("sortviz: -n must be positive and -every not negative")
+	}
+	if args[0] == "table" {
+		return vizTable(stdout, *n, *seed)
+	}
+
+	k := slices.IndexFunc(vizAlgorithms, func(a vizAlgorithm) bool { return a.name == *algo })
+	if k < 0 {
+		var names []string
+		for _, a := range vizAlgorithms {
+			names = append(names, a.name)
+		}
+		return fmt.Errorf("sortviz: unknown algorithm %q, want one of %s", *algo, strings.Join(names, ", "))
+	}
+	data, err := vizInput(*input, *n, rand.New(rand.NewSource(*seed)))
+	if err != nil {
+		return fmt.Errorf("sortviz: %w", err)
+	}
+	if *every == 0 {
+		// Count the swaps first.
+		c := &Counting{Interface: slices.Clone(data)}
+		vizAlgorithms[k].sort(c)
+		*every = max(1, c.Swaps/maxFrames)
+	}
+
+	if args[0] == "frames" {
+		return vizFrames(stdout, data, vizAlgorithms[k].sort, *every)
+	}
+	if *output == "" {
+		return errors.New("sortviz gif: missing -o")
+	}
+	f, err := os.Create(*output)
+	if err != nil {
+		return err
+	}
+	err = vizGIF(f, data, vizAlgorithms[k].sort, *every, *delay)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// vizTable prints the comparison table of all algorithms on every input.
+func vizTable(w io.Writer, n int, seed int64) error {
+	for _, kind := range vizInputs {
+		fmt.Fprintf(w, "%s input, %d elements\n", kind, n)
+		fmt.Fprintf(w, "%-12s %12s %12s %12s\n", "Algorithm", "Less", "Swap", "Time")
+		data, err := vizInput(kind, n, rand.New(rand.NewSource(seed)))
+		if err != nil {
+			return err
+		}
+		for _, a := range vizAlgorithms {
+			if a.name == "insertion" && n > 20000 {
+				fmt.Fprintf(w, "%-12s %12s\n", a.name, "(too slow)")
+				continue
+			}
+			c := &Counting{Interface: slices.Clone(data)}
+			start := time.Now()
+			a.sort(c)
+			elapsed := time.Since(start)
+			if !sort.IsSorted(c.Interface) {
+				return fmt.Errorf("sortviz: %s did not sort the %s input", a.name, kind)
+			}
+			fmt.Fprintf(w, "%-12s %12d %12d %12v\n", a.name, c.Lesses, c.Swaps, elapsed.Round(time.Microsecond))
+		}
+		fmt.Fprintln(w)
+	}
+	return nil
+}
+
+// bars are the characters of increasing height used by vizFrames.
+var bars = []rune("▁▂▃▄▅▆▇█")
+
+// vizFrames prints data as a line of bars before sorting and after every
+// every swaps, and the counts once it is sorted.
+func vizFrames(w io.Writer, data vizData, sortFunc func(RadixInterface), every int) error {
+	top := max(slices.Max(data), 1)
+	frame := func() {
+		line := make([]rune, len(data))
+		for i, v := range data {
+			line[i] = bars[v*(len(bars)-1)/top]
+		}
+		fmt.Fprintln(w, string(line))
+	}
+	frame()
+	c := &Counting{Interface: data}
+	c.OnSwap = func(i, j int) {
+		if c.Swaps%every == 0 {
+			frame()
+		}
+	}
+	sortFunc(c)
+	if c.Swaps%every != 0 {
+		frame()
+	}
+	_, err := fmt.Fprintf(w, "%d Less, %d Swap\n", c.Lesses, c.Swaps)
+	return err
+}
+
+// vizGIF writes the frames of vizFrames as an animated GIF, showing the
+// bars of the last swap in red.
+func vizGIF(w io.Writer, data vizData, sortFunc func(RadixInterface), every, delay int) error {
+	const height = 128
+	barWidth := max(1, 512/len(data))
+	top := max(slices.Max(data), 1)
+	palette := color.Palette{color.White, color.Black, color.RGBA{0xd0, 0x20, 0x20, 0xff}}
+	anim := &gif.GIF{}
+	frame := func(i, j int) {
+		img := image.NewPaletted(image.Rect(0, 0, len(data)*barWidth, height), palette)
+		for k, v := range data {
+			c := uint8(1)
+			if k == i || k == j {
+				c = 2
+			}
+			h := 1 + v*(height-1)/top
+			for x := k * barWidth; x < (k+1)*barWidth; x++ {
+				for y := height - h; y < height; y++ {
+					img.SetColorIndex(x, y, c)
+				}
+			}
+		}
+		anim.Image = append(anim.Image, img)
+		anim.Delay = append(anim.Delay, delay)
+	}
+	frame(-1, -1)
+	c := &Counting{Interface: data}
+	c.OnSwap = func(i, j int) {
+		if c.Swaps%every == 0 {
+			frame(i, j)
+		}
+	}
+	sortFunc(c)
+	frame(-1, -1)
+	anim.Delay[len(anim.Delay)-1] = 100 * delay // linger on the result
+	return gif.EncodeAll(w, anim)
+}
@@ -0,0 +1,266 @@
+package sorting
+
+/*
+Sort specs
+==========
+
+Writing a comparator for every field of every struct is repetitive. A sort
+spec names the fields instead:
+
+	"language,-lines,user"
+
+sorts by language, then by decreasing lines, then by user. A field is named
+by its `sort:"name"` struct tag if it has one, and by its Go name otherwise,
+ignoring case; a tag of "-" hides the field. Fields of embedded structs are
+found as Go finds promoted fields, and unexported fields can be used, so the
+spec can sort the types of the package that defines them.
+
+Compiling a spec
+================
+
+Compile checks the spec against the struct type once, with reflection. A
+field that does not exist or is not orderable (a number, a string or a bool)
+is reported as an error wrapping ErrUnknownField or ErrNotOrderable, and two
+fields with the same name as one wrapping ErrDuplicateField, rather than
+showing up as a panic or a surprising order in the middle of a sort.
+
+The result of the check is a list of keys, each the index path of a field in
+the struct (as in reflect.StructField.Index), its kind and its direction.
+Comparing two values then follows those paths with reflect.Value.Field,
+without looking the fields up by name again. Compiled specs are cached by type
+and spec, so calling Compile again with the same arguments is cheap.
+*/
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+	"sync"
+)
+
+var (
+	ErrUnknownField   = errors.New("unknown field")
+	ErrNotOrderable   = errors.New("field is not orderable")
+	ErrDuplicateField = errors.New("two fields have the same name")
+)
+
+// A SpecError reports a problem with a sort spec.
+type SpecError struct {
+	Type  reflect.Type // the type being sorted
+	Field string       // the field of the spec with the problem
+	Err   error
+}
+
+func (e *SpecError) Error() string {
+	return fmt.Sprintf("sort spec for %v: %q: %v", e.Type, e.Field, e.Err)
+}
+
+func (e *SpecError) Unwrap() error { return e.Err }
+
+// A specKey is a compiled field of a spec.
+type specKey struct {
+	index []int // of the field, as for reflect.Value.FieldByIndex
+	kind  reflect.Kind
+	desc  bool
+}
+
+// A compiledSpec compares structs by their keys.
+type compiledSpec struct {
+	keys []specKey
+	ptr  bool // the values are pointers to the structs
+}
+
+// compare compares the structs a and b, or the structs they point to.
+func (s *compiledSpec) compare(a, b reflect.Value) int {
+	if s.ptr {
+		switch {
+		case a.IsNil() && b.IsNil():
+			return 0
+		case a.IsNil():
+			return -1
+		case b.IsNil():
+			return 1
+		}
+		a, b = a.Elem(), b.Elem()
+	}
+	for _, k := range s.keys {
+		if r := k.compare(field(a, k.index), field(b, k.index)); r != 0 {
+			if k.desc {
+				return -r
+			}
+			return r
+		}
+	}
+	return 0
+}
+
+// field returns the field of the struct v at index. Unlike
+// v.FieldByIndex, it does not check for embedded pointers, which specFields
+// leaves out.
+func field(v reflect.Value, index []int) reflect.Value {
+	for _, i := range index {
+		v = v.Field(i)
+	}
+	return v
+}
+
+// compare compares the fields a and b.
+func (k specKey) compare(a, b reflect.Value) int {
+	switch k.kind {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return cmp.Compare(a.Int(), b.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return cmp.Compare(a.Uint(), b.Uint())
+	case reflect.Float32, reflect.Float64:
+		return cmp.Compare(a.Float(), b.Float())
+	case reflect.String:
+		return strings.Compare(a.String(), b.String())
+	case reflect.Bool:
+		x, y := a.Bool(), b.Bool()
+		switch {
+		case x == y:
+			return 0
+		case y:
+			return -1
+		}
+		return 1
+	}
+	panic("sorting: unexpected kind " + k.kind.String())
+}
+
+// orderable reports whether values of kind k can be compared by a specKey.
+func orderable(k reflect.Kind) bool {
+	switch k {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+		reflect.Float32, reflect.Float64, reflect.String, reflect.Bool:
+		return true
+	}
+	return false
+}
+
+// specCache holds the compiled specs by specCacheKey.
+var specCache sync.Map
+
+type specCacheKey struct {
+	t    reflect.Type
+	spec string
+}
+
+// Compile returns the Comparator for the sort spec, for T a struct type or
+// a pointer to one. Nil pointers sort first.
+func Compile[T any](spec string) (Comparator[T], error) {
+	t := reflect.TypeFor[T]()
+	s, err := compileSpec(t, spec)
+	if err != nil {
+		return nil, err
+	}
+	return func(a, b T) int {
+		return s.compare(reflect.ValueOf(&a).Elem(), reflect.ValueOf(&b).Elem())
+	}, nil
+}
+
+// MustCompile is like Compile but panics if the spec is invalid. It is
+// meant for specs that are constants of the program.
+func MustCompile[T any](spec string) Comparator[T] {
+	c, err := Compile[T](spec)
+	if err != nil {
+		panic(err)
+	}
+	return c
+}
+
+// SortBySpec sorts s stably by the sort spec.
+func SortBySpec[T any](s []T, spec string) error {
+	c, err := Compile[T](spec)
+	if err != nil {
+		return err
+	}
+	slices.SortStableFunc(s, c)
+	return nil
+}
+
+// compileSpec returns the compiled spec for t, from the cache if possible.
+func compileSpec(t reflect.Type, spec string) (*compiledSpec, error) {
+	key := specCacheKey{t, spec}
+	if s, ok := specCache.Load(key); ok {
+		return s.(*compiledSpec), nil
+	}
+
+	s := new(compiledSpec)
+	st := t
+	if st.Kind() == reflect.Pointer {
+		st, s.ptr = st.Elem(), true
+	}
+	if st.Kind() != reflect.Struct {
+		return nil, &SpecError{t, spec, errors.New("not a struct type")}
+	}
+	fields, err := specFields(st)
+	if err != nil {
+		return nil, err
+	}
+	for _, name := range strings.Split(spec, ",") {
+		name = strings.TrimSpace(name)
+		var k specKey
+		switch {
+		case strings.HasPrefix(name, "-"):
+			name, k.desc = name[1:], true
+		case strings.HasPrefix(name, "+"):
+			name = name[1:]
+		}
+		f, ok := fields[strings.ToLower(name)]
+		if !ok {
+			return nil, &SpecError{t, name, ErrUnknownField}
+		}
+		if k.kind = f.Type.Kind(); !orderable(k.kind) {
+			return nil, &SpecError{t, name, fmt.Errorf("%w: %v", ErrNotOrderable, f.Type)}
+		}
+		k.index = f.Index
+		s.keys = append(s.keys, k)
+	}
+
+	specCache.Store(key, s)
+	return s, nil
+}
+
+// specFields returns the fields of the struct type t that specs can name, by
+// lower-case name. Promoted fields through embedded pointers are left out,
+// since they are not stored in the struct itself. Two fields with the same
+// name, such as a field tagged with the name of another, are an error
+// wrapping ErrDuplicateField.
+func specFields(t reflect.Type) (map[string]reflect.StructField, error) {
+	fields := make(map[string]reflect.StructField)
+	for _, f := range reflect.VisibleFields(t) {
+		if f.Anonymous && f.Type.Kind() == reflect.Struct || throughPointer(t, f.Index) {
+			continue
+		}
+		name := f.Name
+		if tag, ok := f.Tag.Lookup("sort"); ok {
+			if tag == "-" {
+				continue
+			}
+			name = tag
+		}
+		key := strings.ToLower(name)
+		if other, ok := fields[key]; ok {
+			return nil, &SpecError{t, name, fmt.Errorf("%w: %s and %s", ErrDuplicateField, other.Name, f.Name)}
+		}
+		fields[key] = f
+	}
+	return fields, nil
+}
+
+// throughPointer reports whether the field at index is reached through an
+// embedded pointer.
+func throughPointer(t reflect.Type, index []int) bool {
+	for _, i := range index[:len(index)-1] {
+		t = t.Field(i).Type
+		if t.Kind() == reflect.Pointer {
+			return true
+		}
+	}
+	return false
+}
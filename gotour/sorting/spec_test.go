@@ -0,0 +1,60 @@
+package sorting
+
+import (
+	"errors"
+	"slices"
+	"testing"
+)
+
+type specBase struct {
+	id uint8
+}
+
+type specItem struct {
+	specBase
+	name   string
+	weight float32
+	ok     bool
+}
+
+func TestCompile(t *testing.T) {
+	items := []*specItem{
+		{specBase{2}, "b", 1.5, true},
+		nil,
+		{specBase{1}, "a", 1.5, false},
+		{specBase{3}, "a", 2, true},
+	}
+	if err := SortBySpec(items, "-weight,ok,id"); err != nil {
+		t.Fatal(err)
+	}
+	var got []uint8
+	for _, it := range items[1:] {
+		got = append(got, it.id)
+	}
+	if items[0] != nil || !slices.Equal(got, []uint8{3, 1, 2}) {
+		t.Errorf("sorted to %v, nil first: %v; want [3 1 2]", got, items[0] == nil)
+	}
+}
+
+func TestCompileErrors(t *testing.T) {
+	type dup struct {
+		Author string `sort:"user"`
+		User   string
+	}
+	for _, tt := range []struct {
+		spec string
+		err  error
+		f    func(string) error
+	}{
+		{"name,size", ErrUnknownField, func(s string) error { _, err := Compile[specItem](s); return err }},
+		{"specbase", ErrUnknownField, func(s string) error { _, err := Compile[specItem](s); return err }},
+		{"user", ErrDuplicateField, func(s string) error { _, err := Compile[dup](s); return err }},
+		{"author", ErrDuplicateField, func(s string) error { _, err := Compile[dup](s); return err }},
+	} {
+		err := tt.f(tt.spec)
+		var se *SpecError
+		if !errors.Is(err, tt.err) || !errors.As(err, &se) {
+			t.Errorf("Compile(%q) = %v, want a SpecError wrapping %v", tt.spec, err, tt.err)
+		}
+	}
+}
@@ -0,0 +1,44 @@
+package sortingseraching
+
+/*
+The multiSorter above leaves an exercise for the reader: use comparison
+functions returning -1, 0 or 1, so that each key is compared only once. The
+sorting package does this for any type with sorting.Comparator chains, and
+the result can be passed to slices.SortFunc and slices.SortStableFunc.
+
+BenchmarkOrderedBy and BenchmarkComparatorChain compare the two; run them with
+
+	go test -bench . gotour/sortingseraching
+*/
+
+import (
+	"fmt"
+	"slices"
+
+	"gotour/sorting"
+)
+
+var (
+	byUser     = sorting.Key(func(c Change) string { return c.user })
+	byLanguage = sorting.Key(func(c Change) string { return c.language })
+	byLines    = sorting.Key(func(c Change) int { return c.lines })
+)
+
+// ComparatorChainExample sorts the changes as MultiSorterExample does, with
+// comparator chains.
+func ComparatorChainExample() {
+	slices.SortFunc(changes, sorting.By(byUser))
+	fmt.Println("By user:", changes)
+
+	slices.SortFunc(changes, sorting.By(byUser, byLines))
+	fmt.Println("By user,<lines:", changes)
+
+	slices.SortFunc(changes, sorting.By(byUser).Then(byLines.Reverse()))
+	fmt.Println("By user,>lines:", changes)
+
+	slices.SortFunc(changes, sorting.By(byLanguage, byLines))
+	fmt.Println("By language,<lines:", changes)
+
+	slices.SortStableFunc(changes, sorting.By(byLanguage, byLines, byUser))
+	fmt.Println("By language,<lines,user:", changes)
+}
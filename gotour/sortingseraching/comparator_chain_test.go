@@ -0,0 +1,50 @@
+package sortingseraching
+
+import (
+	"math/rand"
+	"slices"
+	"testing"
+
+	"gotour/sorting"
+)
+
+// randomChanges returns n random changes with few distinct languages and
+// users, so that the later keys are often needed.
+func randomChanges(n int, rng *rand.Rand) []Change {
+	users := []string{"dmr", "glenda", "gri", "ken", "r", "rsc"}
+	languages := []string{"C", "Go", "Smalltalk"}
+	cs := make([]Change, n)
+	for i := range cs {
+		cs[i] = Change{users[rng.Intn(len(users))], languages[rng.Intn(len(languages))], 10 * rng.Intn(20)}
+	}
+	return cs
+}
+
+// benchmarkSort measures sorting 10000 random changes with sort.
+func benchmarkSort(b *testing.B, sort func([]Change)) {
+	data := randomChanges(10000, rand.New(rand.NewSource(1)))
+	work := make([]Change, len(data))
+	for i := 0; i < b.N; i++ {
+		b.StopTimer()
+		copy(work, data)
+		b.StartTimer()
+		sort(work)
+	}
+}
+
+func BenchmarkOrderedBy(b *testing.B) {
+	language := func(c1, c2 *Change) bool { return c1.language < c2.language }
+	lines := func(c1, c2 *Change) bool { return c1.lines < c2.lines }
+	user := func(c1, c2 *Change) bool { return c1.user < c2.user }
+	benchmarkSort(b, func(cs []Change) { OrderedBy(language, lines, user).Sort(cs) })
+}
+
+func BenchmarkComparatorChain(b *testing.B) {
+	chain := sorting.By(byLanguage, byLines, byUser)
+	b.Run("SortFunc", func(b *testing.B) {
+		benchmarkSort(b, func(cs []Change) { slices.SortFunc(cs, chain) })
+	})
+	b.Run("SortStableFunc", func(b *testing.B) {
+		benchmarkSort(b, func(cs []Change) { slices.SortStableFunc(cs, chain) })
+	})
+}
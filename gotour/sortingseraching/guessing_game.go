@@ -1,17 +1,235 @@
 package sortingseraching
 
+/*
+The guessing game
+=================
+
+GuessingGame finds a number the player has picked by asking whether it is at
+most some value, as sort.Search does. The game is played by a guessingGame,
+which reads the answers from an io.Reader and writes the questions to an
+io.Writer, so that it can be played from a terminal or by a script, over any
+range of numbers.
+
+Answers must be y, yes, n or no, in any case; anything else is asked again.
+
+Lies
+====
+
+When the player is honest, every answer halves the range of possible numbers.
+Once a single number is left, the game asks whether it is the player's. A
+"no" means that some answer was wrong, and the game reports the
+inconsistency rather than a wrong number.
+
+In Ulam's game, the player may lie up to Lies times. The game cannot just
+halve the range then: a number is still possible as long as the answers it
+contradicts are no more than Lies. The game keeps, for every stretch of
+numbers, how many answers they contradict, and asks about the value that
+splits the possibilities evenly, weighing each number by the lies it has
+left. When a single number is possible, that is the answer; if none is, the
+player lied more than allowed.
+*/
+
 import (
+	"bufio"
+	"errors"
+	"flag"
 	"fmt"
-	"sort"
+	"io"
+	"math"
+	"os"
+	"strings"
 )
 
+// ErrInconsistent is returned when the answers of a guessing game
+// contradict each other more than the allowed lies explain.
+var ErrInconsistent = errors.New("the answers are inconsistent")
+
+// A GuessConfig configures a guessing game.
+type GuessConfig struct {
+	Min, Max int // the range of numbers to pick from, inclusive
+	Lies     int // the most lies allowed, for Ulam's game
+}
+
+// A segment is a stretch of numbers contradicting the same number of answers.
+type segment struct {
+	lo, hi int // inclusive
+	lies   int
+}
+
+// A guessingGame asks questions and tracks the possible numbers.
+type guessingGame struct {
+	in       *bufio.Scanner
+	out      io.Writer
+	maxLies  int
+	segments []segment // covering [Min, Max] in order
+}
+
+// ask asks question until it gets a yes or no answer.
+func (g *guessingGame) ask(question string) (bool, error) {
+	for {
+		fmt.Fprintf(g.out, "%s [y/n] ", question)
+		if !g.in.Scan() {
+			if err := g.in.Err(); err != nil {
+				return false, err
+			}
+			return false, io.ErrUnexpectedEOF
+		}
+		switch strings.ToLower(strings.TrimSpace(g.in.Text())) {
+		case "y", "yes":
+			return true, nil
+		case "n", "no":
+			return false, nil
+		}
+		fmt.Fprintln(g.out, "Please answer y or n.")
+	}
+}
+
+// weight returns the weight of a number of s: 1 plus the lies it has left,
+// or 0 if it is no longer possible.
+func (g *guessingGame) weight(s segment) float64 {
+	return float64(max(g.maxLies-s.lies+1, 0))
+}
+
+// possible returns the smallest and largest possible numbers and how many
+// there are.
+func (g *guessingGame) possible() (lo, hi, n int) {
+	for _, s := range g.segments {
+		if s.lies > g.maxLies {
+			continue
+		}
+		if n == 0 {
+			lo = s.lo
+		}
+		hi = s.hi
+		n += s.hi - s.lo + 1
+	}
+	return lo, hi, n
+}
+
+// split returns the value m for which "is it <= m?" splits the possible
+// numbers most evenly by weight. There must be two possible numbers or more.
+func (g *guessingGame) split() int {
+	total := 0.0
+	for _, s := range g.segments {
+		total += float64(s.hi-s.lo+1) * g.weight(s)
+	}
+	lo, hi, _ := g.possible()
+	acc := 0.0
+	for _, s := range g.segments {
+		w := g.weight(s)
+		if w == 0 {
+			continue
+		}
+		segWeight := float64(s.hi-s.lo+1) * w
+		if acc+segWeight >= total/2 {
+			// The first number at which the weight reaches half.
+			m := s.lo + int(math.Ceil((total/2-acc)/w)) - 1
+			return min(max(m, lo), hi-1)
+		}
+		acc += segWeight
+	}
+	return hi - 1
+}
+
+// answer records that the answer to "is it <= m?" was yes, adding a lie to
+// the numbers above m, or no, adding a lie to the numbers up to m.
+func (g *guessingGame) answer(m int, yes bool) {
+	var segments []segment
+	for _, s := range g.segments {
+		if s.lo <= m && m < s.hi {
+			segments = append(segments, segment{s.lo, m, s.lies}, segment{m + 1, s.hi, s.lies})
+		} else {
+			segments = append(segments, s)
+		}
+	}
+	for k := range segments {
+		if yes == (segments[k].lo > m) {
+			segments[k].lies++
+		}
+	}
+	g.segments = segments
+}
+
+// PlayGuessingGame plays a guessing game over the range of cfg, reading
+// answers from in and writing questions to out, and returns the number the
+// player picked. It returns ErrInconsistent if the answers contradict each
+// other beyond cfg.Lies lies.
+func PlayGuessingGame(in io.Reader, out io.Writer, cfg GuessConfig) (int, error) {
+	if cfg.Min > cfg.Max || cfg.Lies < 0 {
+		return 0, fmt.Errorf("invalid game: numbers from %d to %d with %d lies", cfg.Min, cfg.Max, cfg.Lies)
+	}
+	g := &guessingGame{
+		in:       bufio.NewScanner(in),
+		out:      out,
+		maxLies:  cfg.Lies,
+		segments: []segment{{cfg.Min, cfg.Max, 0}},
+	}
+	fmt.Fprintf(out, "Pick an integer from %d to %d.\n", cfg.Min, cfg.Max)
+	if cfg.Lies > 0 {
+		times := "times"
+		if cfg.Lies == 1 {
+			times = "time"
+		}
+		fmt.Fprintf(out, "You may lie up to %d %s.\n", cfg.Lies, times)
+	}
+
+	questions := 0
+	for {
+		lo, _, n := g.possible()
+		if n == 0 {
+			return 0, ErrInconsistent
+		}
+		if n == 1 {
+			if cfg.Lies == 0 {
+				// An honest player can confirm the answer.
+				yes, err := g.ask(fmt.Sprintf("Is your number %d?", lo))
+				if err != nil {
+					return 0, err
+				}
+				if !yes {
+					return 0, ErrInconsistent
+				}
+			}
+			fmt.Fprintf(out, "Your number is %d. (%d questions)\n", lo, questions)
+			return lo, nil
+		}
+		m := g.split()
+		yes, err := g.ask(fmt.Sprintf("Is your number <= %d?", m))
+		if err != nil {
+			return 0, err
+		}
+		g.answer(m, yes)
+		questions++
+	}
+}
+
+// GuessCommand runs the guess command with the given arguments:
+//
+//	guess [-min n] [-max n] [-lies n]
+//
+// plays a guessing game for a number from min to max, reading the answers
+// from stdin and writing the questions to stdout. With -lies, the player may
+// lie up to that many times, as in Ulam's game.
+func GuessCommand(args []string, stdin io.Reader, stdout io.Writer) error {
+	fs := flag.NewFlagSet("guess", flag.ContinueOnError)
+	var cfg GuessConfig
+	fs.IntVar(&cfg.Min, "min", 0, "smallest number to pick")
+	fs.IntVar(&cfg.Max, "max", 100, "largest number to pick")
+	fs.IntVar(&cfg.Lies, "lies", 0, "lies allowed (Ulam's game)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() > 0 {
+		return errors.New("usage: guess [-min n] [-max n] [-lies n]")
+	}
+	_, err := PlayGuessingGame(stdin, stdout, cfg)
+	return err
+}
+
+// GuessingGame plays a guessing game for a number from 0 to 100 on the
+// terminal.
 func GuessingGame() {
-	var s string
-	fmt.Printf("Pick an integer from 0 to 100.\n")
-	answer := sort.Search(100, func(i int) bool {
-		fmt.Printf("Is your number <= %d? ", i)
-		fmt.Scanf("%s", &s)
-		return s != "" && s[0] == 'y'
-	})
-	fmt.Printf("Your number is %d.\n", answer)
+	if _, err := PlayGuessingGame(os.Stdin, os.Stdout, GuessConfig{Min: 0, Max: 100}); err != nil {
+		fmt.Println(err)
+	}
 }
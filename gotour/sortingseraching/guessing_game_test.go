@@ -0,0 +1,133 @@
+package sortingseraching
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+)
+
+// A player answers the questions of a guessing game about secret as they
+// are written, lying in answer to the questions numbered in lies (from 0).
+type player struct {
+	secret    int
+	lies      map[int]bool
+	questions int
+	pending   bytes.Buffer // answers not read yet
+	out       bytes.Buffer // everything the game wrote
+}
+
+func (p *player) Write(b []byte) (int, error) {
+	p.out.Write(b)
+	var m int
+	var yes bool
+	switch s := string(b); {
+	case sscan(s, "Is your number <= %d? [y/n] ", &m):
+		yes = p.secret <= m
+		if p.lies[p.questions] {
+			yes = !yes
+		}
+		p.questions++
+	case sscan(s, "Is your number %d? [y/n] ", &m):
+		yes = p.secret == m
+	default:
+		return len(b), nil
+	}
+	if yes {
+		p.pending.WriteString("yes\n")
+	} else {
+		p.pending.WriteString("no\n")
+	}
+	return len(b), nil
+}
+
+func (p *player) Read(b []byte) (int, error) {
+	if p.pending.Len() == 0 {
+		return 0, io.EOF
+	}
+	return p.pending.Read(b)
+}
+
+// sscan reports whether s matches format in full.
+func sscan(s, format string, m *int) bool {
+	n, err := fmt.Sscanf(s, format, m)
+	return n == 1 && err == nil && fmt.Sprintf(format, *m) == s
+}
+
+func TestGuessingGameHonest(t *testing.T) {
+	for secret := 0; secret <= 100; secret++ {
+		p := &player{secret: secret}
+		got, err := PlayGuessingGame(p, p, GuessConfig{Min: 0, Max: 100})
+		if err != nil || got != secret {
+			t.Fatalf("secret %d: got %d, %v\n%s", secret, got, err, p.out.String())
+		}
+		if p.questions > 7 {
+			t.Errorf("secret %d: %d questions; want at most 7", secret, p.questions)
+		}
+	}
+}
+
+func TestGuessingGameLies(t *testing.T) {
+	for lies := 1; lies <= 2; lies++ {
+		for secret := 1; secret <= 50; secret += 7 {
+			for first := 0; first < 8; first++ {
+				p := &player{secret: secret, lies: map[int]bool{first: true}}
+				if lies == 2 {
+					p.lies[first+3] = true
+				}
+				got, err := PlayGuessingGame(p, p, GuessConfig{Min: 1, Max: 50, Lies: lies})
+				if err != nil || got != secret {
+					t.Fatalf("%d lies, secret %d, lying at %v: got %d, %v\n%s",
+						lies, secret, p.lies, got, err, p.out.String())
+				}
+			}
+		}
+	}
+}
+
+func TestGuessingGameTooManyLies(t *testing.T) {
+	// An honest game cannot find a number after a lie; the confirmation
+	// question catches it.
+	p := &player{secret: 30, lies: map[int]bool{0: true}}
+	if got, err := PlayGuessingGame(p, p, GuessConfig{Min: 0, Max: 100}); !errors.Is(err, ErrInconsistent) {
+		t.Errorf("got %d, %v; want ErrInconsistent", got, err)
+	}
+}
+
+func TestGuessingGameReprompts(t *testing.T) {
+	var out strings.Builder
+	in := strings.NewReader("maybe\n\nYES\n  n \ny\n")
+	got, err := PlayGuessingGame(in, &out, GuessConfig{Min: 1, Max: 3})
+	if err != nil || got != 2 {
+		t.Fatalf("got %d, %v; want 2", got, err)
+	}
+	want := `Pick an integer from 1 to 3.
+Is your number <= 2? [y/n] Please answer y or n.
+Is your number <= 2? [y/n] Please answer y or n.
+Is your number <= 2? [y/n] Is your number <= 1? [y/n] Is your number 2? [y/n] Your number is 2. (2 questions)
+`
+	if out.String() != want {
+		t.Errorf("output:\n%s\nwant:\n%s", out.String(), want)
+	}
+}
+
+func TestGuessCommand(t *testing.T) {
+	var out strings.Builder
+	if err := GuessCommand([]string{"-min", "5", "-max", "6"}, strings.NewReader("n\ny\n"), &out); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasSuffix(out.String(), "Your number is 6. (1 questions)\n") {
+		t.Errorf("output:\n%s", out.String())
+	}
+	if err := GuessCommand([]string{"-max", "1"}, strings.NewReader("maybe\n"), io.Discard); err != io.ErrUnexpectedEOF {
+		t.Errorf("input ending unanswered: got %v; want %v", err, io.ErrUnexpectedEOF)
+	}
+	if err := GuessCommand([]string{"-min", "3", "-max", "2"}, strings.NewReader(""), io.Discard); err == nil {
+		t.Error("empty range: got no error")
+	}
+	if err := GuessCommand([]string{"extra"}, strings.NewReader(""), io.Discard); err == nil {
+		t.Error("extra argument: got no error")
+	}
+}
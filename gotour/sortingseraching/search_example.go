@@ -0,0 +1,36 @@
+package sortingseraching
+
+/*
+BinarySearchExample uses sort.Search directly. The search package names the
+usual searches on sorted data, and adds galloping, interpolation and
+bisection over floats.
+*/
+
+import (
+	"fmt"
+	"math"
+
+	"gotour/search"
+)
+
+// SearchExample shows the searches of the search package.
+func SearchExample() {
+	data := []int{1, 2, 3, 3, 3, 4, 23}
+	lo, hi := search.EqualRange(data, 3)
+	fmt.Printf("3 is at data[%d:%d]; 21 would be inserted at %d\n", lo, hi, search.LowerBound(data, 21))
+
+	// The smallest power of two that is at least a million, without knowing
+	// how far to look.
+	fmt.Println("First power of two >= 1e6: 2 **", search.Exponential(func(i int) bool { return 1<<i >= 1000000 }))
+
+	squares := make([]int, 1000)
+	for i := range squares {
+		squares[i] = i * i
+	}
+	i, found := search.Interpolation(squares, 250000)
+	fmt.Println("250000 is a square:", found, "of", i)
+
+	// The yearly interest rate at which 1000 doubles in 10 years.
+	rate, err := search.Bisect(0, 1, 1e-6, func(r float64) bool { return 1000*math.Pow(1+r, 10) >= 2000 })
+	fmt.Printf("Doubling in 10 years takes %.4f%% a year (%v)\n", 100*rate, err)
+}
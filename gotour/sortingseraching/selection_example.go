@@ -0,0 +1,32 @@
+package sortingseraching
+
+/*
+Finding the median or the largest few elements does not need a full sort. The
+sorting package selects them in linear time.
+*/
+
+import (
+	"fmt"
+	"slices"
+
+	"gotour/sorting"
+)
+
+// SelectionExample finds the median and the largest values of a slice, and
+// the biggest changes.
+func SelectionExample() {
+	data := []int{31, 42, 17, 26, 8, 99, 53, 3, 64, 12, 77}
+
+	s := slices.Clone(data)
+	mid := len(s) / 2
+	sorting.NthElement(s, mid)
+	fmt.Println("Median:", s[mid])
+
+	sorting.PartialSort(s, 3)
+	fmt.Println("Three smallest:", s[:3])
+
+	fmt.Println("Three largest:", sorting.TopKOf(slices.Values(data), 3))
+
+	biggest := sorting.TopKOfFunc(slices.Values(changes), 2, sorting.By(byLines, byUser.Reverse()))
+	fmt.Println("Two biggest changes:", biggest)
+}
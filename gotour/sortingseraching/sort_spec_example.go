@@ -0,0 +1,50 @@
+package sortingseraching
+
+/*
+The closures of MultiSorterExample and SortKeysExample all do the same thing
+for different fields. With sorting.SortBySpec the fields are named in a
+string instead, such as "language,-lines,user", and the comparator is built
+by reflection. A `sort:"..."` struct tag gives a field another name in specs.
+*/
+
+import (
+	"fmt"
+
+	"gotour/sorting"
+)
+
+// A Commit is a Change with its fields named for sort specs.
+type Commit struct {
+	Author string `sort:"user"`
+	Lang   string `sort:"language"`
+	Lines  int
+}
+
+// SortSpecExample sorts changes, planets and commits by sort specs.
+func SortSpecExample() {
+	for _, spec := range []string{"user", "user,lines", "user,-lines", "language,lines", "language,-lines,user"} {
+		if err := sorting.SortBySpec(changes, spec); err != nil {
+			fmt.Println(err)
+			continue
+		}
+		fmt.Printf("By %s: %v\n", spec, changes)
+	}
+
+	for _, spec := range []string{"name", "mass", "-distance"} {
+		if err := sorting.SortBySpec(planets, spec); err != nil {
+			fmt.Println(err)
+			continue
+		}
+		fmt.Printf("By %s: %v\n", spec, planets)
+	}
+
+	commits := []Commit{{"gri", "Go", 100}, {"ken", "C", 150}, {"r", "Go", 100}, {"dmr", "C", 100}}
+	if err := sorting.SortBySpec(commits, "language,-lines,user"); err != nil {
+		fmt.Println(err)
+		return
+	}
+	fmt.Println("Commits by language,-lines,user:", commits)
+
+	// Mistakes in a spec are reported before anything is sorted.
+	fmt.Println(sorting.SortBySpec(changes, "language,size"))
+}